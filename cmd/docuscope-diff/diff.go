@@ -0,0 +1,360 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+/*
+Snapshot is the content of a dictionary or rules file that is compared.
+Lats are the LATs with patterns.  LatFiles are every LAT file, including
+those without patterns, and are only known for a dictionary.
+Patterns are the words of a pattern joined by spaces mapped to the set of
+LATs that use it.
+Classes are the members of each word class as given by the words map.
+Tones maps each LAT to its Cluster/Dimension and is nil if not known.
+*/
+type Snapshot struct {
+	Path     string
+	Lats     map[string]bool
+	LatFiles map[string]bool
+	Patterns map[string]map[string]bool
+	Classes  map[string][]string
+	Tones    map[string]string
+}
+
+/**
+ * Loads a dictionary directory or archive, or a rules JSON file.
+ * Dictionaries are converted the same way as docuscope-rules, using the
+ * keep-all policy so every single word pattern is kept.
+ */
+func loadSnapshot(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	if info.IsDir() || dictionary.IsArchive(path) {
+		d, err := dictionary.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		return dictionarySnapshot(d)
+	}
+	dict, err := rules.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(path, dict, nil, nil), nil
+}
+
+func dictionarySnapshot(d *dictionary.Dictionary) (*Snapshot, error) {
+	dict, err := rules.Build(d, rules.KeepAll)
+	if err != nil {
+		return nil, err
+	}
+	lats := make([]string, len(d.Lats))
+	for i, lat := range d.Lats {
+		lats[i] = lat.Name
+	}
+	return newSnapshot(d.Directory, dict, lats, d.Tones), nil
+}
+
+/**
+ * Collects the patterns and classes of the rules.
+ *
+ * @param lats: the LATs of the dictionary, including any without patterns,
+ *   or nil for rules.
+ * @param t: the tones of the dictionary or nil if unknown.
+ */
+func newSnapshot(path string, dict *rules.DocuScopeDictionary, lats []string, t tones.Tones) *Snapshot {
+	s := &Snapshot{
+		Path:     path,
+		Lats:     make(map[string]bool),
+		Patterns: make(map[string]map[string]bool),
+		Classes:  make(map[string][]string),
+	}
+	if lats != nil {
+		s.LatFiles = make(map[string]bool)
+		for _, lat := range lats {
+			s.LatFiles[lat] = true
+		}
+	}
+	add := func(lat string, words ...string) {
+		pattern := strings.Join(words, " ")
+		if s.Patterns[pattern] == nil {
+			s.Patterns[pattern] = make(map[string]bool)
+		}
+		s.Patterns[pattern][lat] = true
+		s.Lats[lat] = true
+	}
+	for first, seconds := range dict.Rules {
+		for second, latPatterns := range seconds {
+			for lat, patterns := range latPatterns {
+				for _, rest := range patterns {
+					add(lat, append([]string{first, second}, rest...)...)
+				}
+			}
+		}
+	}
+	for _, word := range dict.ShortRules.Words() {
+		for _, lat := range dict.ShortRules.Get(word) {
+			add(lat, word)
+		}
+	}
+	for word, classes := range dict.Words {
+		for _, class := range classes[1:] {
+			s.Classes[class] = append(s.Classes[class], word)
+		}
+	}
+	for _, members := range s.Classes {
+		sort.Strings(members)
+	}
+	if t != nil {
+		s.Tones = make(map[string]string)
+		for cluster, dimensions := range t {
+			for dimension, lats := range dimensions {
+				for _, lat := range lats {
+					s.Tones[lat] = joinTone(s.Tones[lat], cluster+"/"+dimension)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// joinTone lists the dimensions of a LAT assigned to more than one.
+func joinTone(tone string, dimension string) string {
+	if tone == "" {
+		return dimension
+	}
+	dimensions := append(strings.Split(tone, ", "), dimension)
+	sort.Strings(dimensions)
+	return strings.Join(dimensions, ", ")
+}
+
+// Report is the differences between an old and a new Snapshot.
+type Report struct {
+	Old         string        `json:"old"`
+	New         string        `json:"new"`
+	Summary     Summary       `json:"summary"`
+	AddedLats   []string      `json:"addedLats"`
+	RemovedLats []string      `json:"removedLats"`
+	Lats        []LatChange   `json:"lats"`
+	Moved       []Move        `json:"moved"`
+	Classes     []ClassChange `json:"classes"`
+	Tones       []ToneChange  `json:"tones"`
+}
+
+// Summary counts the differences.
+type Summary struct {
+	AddedLats       int `json:"addedLats"`
+	RemovedLats     int `json:"removedLats"`
+	AddedPatterns   int `json:"addedPatterns"`
+	RemovedPatterns int `json:"removedPatterns"`
+	MovedPatterns   int `json:"movedPatterns"`
+	AddedClasses    int `json:"addedClasses"`
+	RemovedClasses  int `json:"removedClasses"`
+	ChangedClasses  int `json:"changedClasses"`
+	ToneChanges     int `json:"toneChanges"`
+}
+
+// LatChange is the patterns added to and removed from a LAT.
+type LatChange struct {
+	Lat     string   `json:"lat"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Move is a pattern that is no longer in the From LATs but is now in the To LATs.
+type Move struct {
+	Pattern string   `json:"pattern"`
+	From    []string `json:"from"`
+	To      []string `json:"to"`
+}
+
+// ClassChange is the change in the members of a word class.
+type ClassChange struct {
+	Class   string   `json:"class"`
+	Status  string   `json:"status"` // added, removed, or changed
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ToneChange is a LAT assigned to a different Cluster/Dimension, "" if none.
+type ToneChange struct {
+	Lat string `json:"lat"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+/**
+ * Compares two snapshots.  Everything in the report is sorted.
+ * The LAT files are only compared when both snapshots are dictionaries,
+ * otherwise the LATs with patterns are, as rules do not list the LATs
+ * without patterns.  Tones are only compared when both snapshots have
+ * them.
+ */
+func compare(old *Snapshot, new *Snapshot) *Report {
+	r := &Report{
+		Old:         old.Path,
+		New:         new.Path,
+		AddedLats:   []string{},
+		RemovedLats: []string{},
+		Lats:        []LatChange{},
+		Moved:       []Move{},
+		Classes:     []ClassChange{},
+		Tones:       []ToneChange{},
+	}
+	before, after := old.Lats, new.Lats
+	if old.LatFiles != nil && new.LatFiles != nil {
+		before, after = old.LatFiles, new.LatFiles
+	}
+	for _, lat := range union(before, after) {
+		switch {
+		case !before[lat]:
+			r.AddedLats = append(r.AddedLats, lat)
+		case !after[lat]:
+			r.RemovedLats = append(r.RemovedLats, lat)
+		}
+	}
+
+	changes := make(map[string]*LatChange)
+	change := func(lat string) *LatChange {
+		if changes[lat] == nil {
+			changes[lat] = &LatChange{Lat: lat}
+		}
+		return changes[lat]
+	}
+	patterns := make(map[string]bool)
+	for pattern := range old.Patterns {
+		patterns[pattern] = true
+	}
+	for pattern := range new.Patterns {
+		patterns[pattern] = true
+	}
+	for _, pattern := range keys(patterns) {
+		oldLats, newLats := old.Patterns[pattern], new.Patterns[pattern]
+		removed, added := minus(oldLats, newLats), minus(newLats, oldLats)
+		if len(removed) > 0 && len(added) > 0 {
+			r.Moved = append(r.Moved, Move{pattern, removed, added})
+			continue
+		}
+		for _, lat := range removed {
+			change(lat).Removed = append(change(lat).Removed, pattern)
+			r.Summary.RemovedPatterns++
+		}
+		for _, lat := range added {
+			change(lat).Added = append(change(lat).Added, pattern)
+			r.Summary.AddedPatterns++
+		}
+	}
+	for _, lat := range sortedKeys(changes) {
+		r.Lats = append(r.Lats, *changes[lat])
+	}
+
+	classes := make(map[string]bool)
+	for class := range old.Classes {
+		classes[class] = true
+	}
+	for class := range new.Classes {
+		classes[class] = true
+	}
+	for _, class := range keys(classes) {
+		oldMembers, oldOk := old.Classes[class]
+		newMembers, newOk := new.Classes[class]
+		c := ClassChange{Class: class, Status: "changed",
+			Added:   minus(set(newMembers), set(oldMembers)),
+			Removed: minus(set(oldMembers), set(newMembers))}
+		switch {
+		case !oldOk:
+			c.Status = "added"
+			r.Summary.AddedClasses++
+		case !newOk:
+			c.Status = "removed"
+			r.Summary.RemovedClasses++
+		case len(c.Added) == 0 && len(c.Removed) == 0:
+			continue
+		default:
+			r.Summary.ChangedClasses++
+		}
+		r.Classes = append(r.Classes, c)
+	}
+
+	if old.Tones != nil && new.Tones != nil {
+		lats := make(map[string]bool)
+		for lat := range old.Tones {
+			lats[lat] = true
+		}
+		for lat := range new.Tones {
+			lats[lat] = true
+		}
+		for _, lat := range keys(lats) {
+			if old.Tones[lat] != new.Tones[lat] {
+				r.Tones = append(r.Tones, ToneChange{lat, old.Tones[lat], new.Tones[lat]})
+			}
+		}
+	}
+
+	r.Summary.AddedLats = len(r.AddedLats)
+	r.Summary.RemovedLats = len(r.RemovedLats)
+	r.Summary.MovedPatterns = len(r.Moved)
+	r.Summary.ToneChanges = len(r.Tones)
+	return r
+}
+
+// union lists the keys of both sets in sorted order.
+func union(a map[string]bool, b map[string]bool) []string {
+	u := make(map[string]bool, len(a))
+	for k := range a {
+		u[k] = true
+	}
+	for k := range b {
+		u[k] = true
+	}
+	return keys(u)
+}
+
+// minus lists the keys of a that are not in b in sorted order.
+func minus(a map[string]bool, b map[string]bool) []string {
+	var result []string
+	for k := range a {
+		if !b[k] {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func set(values []string) map[string]bool {
+	s := make(map[string]bool, len(values))
+	for _, v := range values {
+		s[v] = true
+	}
+	return s
+}
+
+func keys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sortedKeys(m map[string]*LatChange) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
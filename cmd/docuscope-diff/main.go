@@ -0,0 +1,158 @@
+/*
+Compare two releases of a DocuScope dictionary.
+
+Usage:
+> docuscope-diff Dictionaries/default-20210924 Dictionaries/default-20220301
+> docuscope-diff --format json old.json.gz new.json.gz > changes.json
+
+Each argument is a dictionary directory or archive, or the (compressed) JSON
+output of docuscope-rules.  Dictionaries are converted in the same way as
+docuscope-rules and the _tones.txt files as docuscope-tones.
+
+Reports LATs that were added or removed, the patterns added to and removed
+from each LAT, patterns that moved from one LAT to another, changes in the
+members of word classes, and LATs assigned to a different tones dimension.
+Tones are only compared between two dictionaries as the rules JSON does not
+include them.
+
+Output formats:
+  - text: summary counts followed by the changes.
+  - json: see Report.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
+)
+
+func main() {
+	var format string
+
+	app := &cli.App{
+		Name:      "DocuScope Dictionary Diff",
+		Usage:     "Reports the changes between two DocuScope dictionaries or rules files.",
+		UsageText: "docuscope-diff [--format text|json] <old> <new>",
+		Version:   "v1.0.0",
+		Authors: []*cli.Author{
+			&cli.Author{
+				Name:  "Michael Ringenberg",
+				Email: unobfuscate.Unobfuscate("ringenbergATcmuDOTedu"),
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "format",
+				Value:       "text",
+				Usage:       "Output `format`: text or json",
+				Destination: &format,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return fmt.Errorf("expected an old and a new dictionary, got %d arguments", c.NArg())
+			}
+			return diffDictionaries(c.Args().Get(0), c.Args().Get(1), format)
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		dicterr.Exit(err)
+	}
+}
+
+func diffDictionaries(oldPath string, newPath string, format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	old, err := loadSnapshot(oldPath)
+	if err != nil {
+		return err
+	}
+	new, err := loadSnapshot(newPath)
+	if err != nil {
+		return err
+	}
+	report := compare(old, new)
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = writeText(os.Stdout, report)
+	}
+	if err != nil {
+		return &dicterr.WriteError{Path: "stdout", Err: err}
+	}
+	return nil
+}
+
+/**
+ * Writes the summary counts and then the changes in a diff like format.
+ */
+func writeText(w io.Writer, r *Report) error {
+	s := r.Summary
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", r.Old, r.New)
+	fmt.Fprintf(&b, "LATs: %d added, %d removed\n", s.AddedLats, s.RemovedLats)
+	fmt.Fprintf(&b, "Patterns: %d added, %d removed, %d moved\n",
+		s.AddedPatterns, s.RemovedPatterns, s.MovedPatterns)
+	fmt.Fprintf(&b, "Word classes: %d added, %d removed, %d changed\n",
+		s.AddedClasses, s.RemovedClasses, s.ChangedClasses)
+	fmt.Fprintf(&b, "Tones: %d LATs reassigned\n", s.ToneChanges)
+
+	for _, lat := range r.AddedLats {
+		fmt.Fprintf(&b, "\n+ LAT %s\n", lat)
+	}
+	for _, lat := range r.RemovedLats {
+		fmt.Fprintf(&b, "\n- LAT %s\n", lat)
+	}
+	for _, lat := range r.Lats {
+		fmt.Fprintf(&b, "\n@ LAT %s\n", lat.Lat)
+		for _, pattern := range lat.Removed {
+			fmt.Fprintf(&b, "- %s\n", pattern)
+		}
+		for _, pattern := range lat.Added {
+			fmt.Fprintf(&b, "+ %s\n", pattern)
+		}
+	}
+	if len(r.Moved) > 0 {
+		b.WriteString("\n@ Moved\n")
+	}
+	for _, m := range r.Moved {
+		fmt.Fprintf(&b, "> %s: %s -> %s\n", m.Pattern,
+			strings.Join(m.From, ", "), strings.Join(m.To, ", "))
+	}
+	for _, c := range r.Classes {
+		fmt.Fprintf(&b, "\n@ CLASS %s (%s)\n", c.Class, c.Status)
+		for _, word := range c.Removed {
+			fmt.Fprintf(&b, "- %s\n", word)
+		}
+		for _, word := range c.Added {
+			fmt.Fprintf(&b, "+ %s\n", word)
+		}
+	}
+	if len(r.Tones) > 0 {
+		b.WriteString("\n@ Tones\n")
+	}
+	for _, t := range r.Tones {
+		fmt.Fprintf(&b, "> %s: %s -> %s\n", t.Lat, none(t.Old), none(t.New))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func none(tone string) string {
+	if tone == "" {
+		return "(none)"
+	}
+	return tone
+}
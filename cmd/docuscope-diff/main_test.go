@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+// next is the test dictionary with Exclamation removed, a pattern moved,
+// and changes to the word classes and tones.
+var next = fstest.MapFS{
+	"_wordclasses.txt":    {Data: []byte("CLASS: GREET\nhello\nhey\n\nCLASS: BANG\nbang\nboom\n\nCLASS: NEW\nx\n")},
+	"_tones.txt":          {Data: []byte("CLUSTER: Confidence\nDIMENSION: Certain\nLAT: Confidence\nLAT*: Uncertainty\n")},
+	"Confidence.txt":      {Data: []byte("I think\nI believe that\n!greet there\ncertainly\nfor sure\nwow\n")},
+	"sub/Uncertainty.txt": {Data: []byte("maybe\n\nI Think So\nperhaps , perhaps\nI think it might\n")},
+}
+
+func TestCompare(t *testing.T) {
+	d, err := dictionary.LoadFS(os.DirFS("../../testdata/dictionary"), "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := dictionarySnapshot(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err = dictionary.LoadFS(next, "new"); err != nil {
+		t.Fatal(err)
+	}
+	new, err := dictionarySnapshot(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := compare(old, new)
+
+	var text bytes.Buffer
+	if err := writeText(&text, report); err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "diff-text.golden"), text.Bytes())
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "diff-json.golden"), append(b, '\n'))
+
+	if empty := compare(old, old); empty.Summary != (Summary{}) {
+		t.Errorf("Expected no differences comparing a dictionary to itself but instead got %+v!", empty.Summary)
+	}
+}
+
+func TestCompareRulesLats(t *testing.T) {
+	withEmpty := fstest.MapFS{
+		"_wordclasses.txt": {Data: []byte("CLASS: GREET\nhello\n")},
+		"Greeting.txt":     {Data: []byte("!GREET there\n")},
+		"Empty.txt":        {Data: []byte("\n")},
+	}
+	d, err := dictionary.LoadFS(withEmpty, "dictionary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := dictionarySnapshot(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := rules.Build(d, rules.KeepAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromRules := newSnapshot("rules.json", built, nil, nil)
+	for _, r := range []*Report{compare(dict, fromRules), compare(fromRules, dict)} {
+		if r.Summary != (Summary{}) {
+			t.Errorf("Expected a LAT without patterns not to differ from rules but instead got %+v!", r.Summary)
+		}
+	}
+
+	delete(withEmpty, "Empty.txt")
+	if d, err = dictionary.LoadFS(withEmpty, "dictionary"); err != nil {
+		t.Fatal(err)
+	}
+	without, err := dictionarySnapshot(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := compare(dict, without); len(r.RemovedLats) != 1 || r.RemovedLats[0] != "Empty" {
+		t.Errorf("Expected the LAT file without patterns to be removed but instead got %v!", r.RemovedLats)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+// Severity levels of issues.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Checks performed by the linter.
+var Checks = []Check{
+	{"undefined-class", SeverityError, "Pattern references a !CLASS that is not defined in _wordclasses.txt."},
+	{"empty-class", SeverityWarning, "Word class has no members."},
+	{"duplicate-pattern", SeverityWarning, "Pattern is repeated within or across LATs."},
+	{"short-rule-collision", SeverityError, "Single word pattern is used by more than one LAT; only one can be a shortRule."},
+	{"lat-name", SeverityError, "LAT file name contains characters that are not valid in LAT ids."},
+	{"duplicate-lat", SeverityError, "More than one LAT file has the same name."},
+}
+
+// Check is a kind of issue that the linter reports.
+type Check struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Issue is a problem found in a dictionary.
+type Issue struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+var (
+	classRe  = regexp.MustCompile(`^!\w`)
+	latIdRe  = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	severity = make(map[string]string)
+)
+
+func init() {
+	for _, c := range Checks {
+		severity[c.ID] = c.Severity
+	}
+}
+
+func newIssue(check string, path string, line int, format string, a ...interface{}) Issue {
+	return Issue{check, severity[check], path, line, fmt.Sprintf(format, a...)}
+}
+
+/**
+ * Checks the dictionary for problems.
+ * Issues are in the order found: word classes, LAT file names, then
+ * patterns in LAT file order.
+ */
+func lint(d *dictionary.Dictionary) ([]Issue, error) {
+	var issues []Issue
+	wordclassesPath := filepath.Join(d.Directory, dictionary.WordClassesFile)
+
+	classes := make(map[string]bool)
+	for _, class := range d.Classes {
+		classes[class.Name] = true
+		if len(class.Members) == 0 {
+			issues = append(issues, newIssue("empty-class", wordclassesPath,
+				class.Line, "word class %s has no members", class.Name))
+		}
+	}
+
+	latPaths := make(map[string]string)
+	for _, lat := range d.Lats {
+		if !latIdRe.MatchString(lat.Name) {
+			issues = append(issues, newIssue("lat-name", lat.Path, 0,
+				"LAT name %q should only contain letters, digits, and underscores", lat.Name))
+		}
+		if other, ok := latPaths[lat.Name]; ok {
+			issues = append(issues, newIssue("duplicate-lat", lat.Path, 0,
+				"LAT %s is also defined by %s", lat.Name, other))
+		} else {
+			latPaths[lat.Name] = lat.Path
+		}
+	}
+
+	seen := make(map[string]dictionary.Pattern)
+	err := d.Walk(func(p dictionary.Pattern) error {
+		for _, w := range p.Words {
+			if classRe.MatchString(w) && !classes[w] {
+				issues = append(issues, newIssue("undefined-class", p.Path, p.Line,
+					"word class %s is not defined", w))
+			}
+		}
+		key := strings.Join(p.Words, " ")
+		first, ok := seen[key]
+		switch {
+		case !ok:
+			seen[key] = p
+		case len(p.Words) == 1 && first.Lat != p.Lat:
+			issues = append(issues, newIssue("short-rule-collision", p.Path, p.Line,
+				"%q is also a pattern for LAT %s at %s:%d", key, first.Lat, first.Path, first.Line))
+		default:
+			issues = append(issues, newIssue("duplicate-pattern", p.Path, p.Line,
+				"%q duplicates the pattern for LAT %s at %s:%d", key, first.Lat, first.Path, first.Line))
+		}
+		return nil
+	})
+	return issues, err
+}
@@ -0,0 +1,193 @@
+/*
+Validate a DocuScope dictionary directory before converting it.
+
+Usage:
+> docuscope-lint Dictionaries/default
+> docuscope-lint --format sarif Dictionaries/default > lint.sarif
+
+Reports patterns that use undefined word classes, empty word classes,
+duplicate patterns, single word patterns that collide in shortRules, and
+LAT file names that are not valid LAT ids.
+The exit status is 65 if any error level issues are found.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
+)
+
+func main() {
+	var format string
+
+	app := &cli.App{
+		Name:      "DocuScope Dictionary Linter",
+		Usage:     "Reports problems in a directory containing LAT files and a _wordclasses.txt file.",
+		UsageText: "docuscope-lint [--format text|json|sarif] Dictionaries/default",
+		Version:   "v1.0.0",
+		Authors: []*cli.Author{
+			&cli.Author{
+				Name:  "Michael Ringenberg",
+				Email: unobfuscate.Unobfuscate("ringenbergATcmuDOTedu"),
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "format",
+				Value:       "text",
+				Usage:       "Output `format`: text, json, or sarif",
+				Destination: &format,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return lintDictionary(c.Args().First(), format)
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		dicterr.Exit(err)
+	}
+}
+
+func lintDictionary(directory string, format string) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	issues, err := lint(d)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "text":
+		err = writeText(os.Stdout, issues)
+	case "json":
+		err = writeJSON(os.Stdout, issues)
+	case "sarif":
+		err = writeSARIF(os.Stdout, issues)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return &dicterr.WriteError{Path: "stdout", Err: err}
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return cli.Exit(fmt.Sprintf("%d errors, %d warnings", errorCount,
+			len(issues)-errorCount), dicterr.ExitBadInput)
+	}
+	return nil
+}
+
+/**
+ * Writes issues as path:line: severity: message [check], one per line.
+ */
+func writeText(w io.Writer, issues []Issue) error {
+	for _, issue := range issues {
+		location := issue.Path
+		if issue.Line > 0 {
+			location = fmt.Sprintf("%s:%d", issue.Path, issue.Line)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s [%s]\n", location,
+			issue.Severity, issue.Message, issue.Check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, issues []Issue) error {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+/**
+ * Writes issues as a SARIF 2.1.0 log for code scanning tools.
+ */
+func writeSARIF(w io.Writer, issues []Issue) error {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type region struct {
+		StartLine int `json:"startLine"`
+	}
+	type artifactLocation struct {
+		URI string `json:"uri"`
+	}
+	type physicalLocation struct {
+		ArtifactLocation artifactLocation `json:"artifactLocation"`
+		Region           *region          `json:"region,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation physicalLocation `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   message    `json:"message"`
+		Locations []location `json:"locations"`
+	}
+	type rule struct {
+		ID               string  `json:"id"`
+		ShortDescription message `json:"shortDescription"`
+	}
+	type driver struct {
+		Name  string `json:"name"`
+		Rules []rule `json:"rules"`
+	}
+	type tool struct {
+		Driver driver `json:"driver"`
+	}
+	type run struct {
+		Tool    tool     `json:"tool"`
+		Results []result `json:"results"`
+	}
+	type log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []run  `json:"runs"`
+	}
+
+	rules := make([]rule, len(Checks))
+	for i, c := range Checks {
+		rules[i] = rule{c.ID, message{c.Description}}
+	}
+	results := make([]result, len(issues))
+	for i, issue := range issues {
+		loc := physicalLocation{ArtifactLocation: artifactLocation{issue.Path}}
+		if issue.Line > 0 {
+			loc.Region = &region{issue.Line}
+		}
+		results[i] = result{issue.Check, issue.Severity, message{issue.Message},
+			[]location{{loc}}}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []run{{
+			Tool:    tool{driver{"docuscope-lint", rules}},
+			Results: results,
+		}},
+	})
+}
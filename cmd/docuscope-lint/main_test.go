@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+)
+
+func TestLintDictionary(t *testing.T) {
+	for _, format := range []string{"text", "json", "sarif"} {
+		actual, err := golden.Stdout(t, func() error {
+			return lintDictionary("../../testdata/dictionary", format)
+		})
+		// The test dictionary has a short-rule-collision error.
+		exit, ok := err.(cli.ExitCoder)
+		if !ok || exit.ExitCode() != dicterr.ExitBadInput {
+			t.Errorf("Expected exit status %d but instead got %v!", dicterr.ExitBadInput, err)
+		}
+		golden.Assert(t, filepath.Join("testdata", "lint-"+format+".golden"), actual)
+	}
+}
+
+func TestLintChecks(t *testing.T) {
+	// Each fixture dictionary in testdata/<check> has one problem.
+	tests := map[string]int{
+		"undefined-class":   dicterr.ExitBadInput,
+		"empty-class":       dicterr.ExitOK,
+		"duplicate-pattern": dicterr.ExitOK,
+		"lat-name":          dicterr.ExitBadInput,
+		"duplicate-lat":     dicterr.ExitBadInput,
+	}
+	for check, code := range tests {
+		actual, err := golden.Stdout(t, func() error {
+			return lintDictionary(filepath.Join("testdata", check), "text")
+		})
+		status := dicterr.ExitOK
+		if exit, ok := err.(cli.ExitCoder); ok {
+			status = exit.ExitCode()
+		} else if err != nil {
+			t.Fatalf("%s: %v", check, err)
+		}
+		if status != code {
+			t.Errorf("%s: Expected exit status %d but instead got %d!", check, code, status)
+		}
+		golden.Assert(t, filepath.Join("testdata", check+".golden"), actual)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+)
+
+func TestGetDictionaryDB(t *testing.T) {
+	actual, err := golden.Stdout(t, func() error {
+		return getDictionaryDB("../../testdata/dictionary", false)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "rules-db.golden"), actual)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+// DefaultBatchSize is the number of patterns sent in each UNWIND query.
+const DefaultBatchSize = 1000
+
+/**
+ * Generates function that will return an UNWIND query, with the same
+ * MERGE shape as memoQuery, that merges a batch of $rows of LAT rules
+ * with the given number of words.
+ */
+func memoBatchQuery(v scope) MemoizedQuery {
+	cache := make(map[int]string)
+	cache[0] = ""
+	return func(index int) string {
+		if val, found := cache[index]; found {
+			return val
+		}
+		result := "UNWIND $rows AS row " + mergeQuery(index, "row.", v)
+		cache[index] = result
+		return result
+	}
+}
+
+/**
+ * Runs an UNWIND query of $rows over the rows in batches, one transaction
+ * for each.
+ *
+ * @param what: the kind of rows for error messages, eg. "word class".
+ */
+func writeRows(session neo4j.Session, query string, rows []map[string]interface{}, batchSize int, what string) error {
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := make([]interface{}, 0, end-start)
+		for _, row := range rows[start:end] {
+			batch = append(batch, row)
+		}
+		_, txerr := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+			_, err := tx.Run(query, map[string]interface{}{"rows": batch})
+			return nil, err
+		})
+		if txerr != nil {
+			fmt.Printf("Error on %s transaction: %v\n", what, txerr)
+			return txerr
+		}
+	}
+	return nil
+}
+
+/**
+ * Collects patterns into batches of the same length and merges each full
+ * batch in its own transaction.  The batches mix the patterns of many LAT
+ * files, so committed is called for a LAT file only once it has been
+ * finished and all of its patterns have been written.
+ */
+type batcher struct {
+	session   neo4j.Session
+	size      int
+	merges    MemoizedQuery
+	version   scope
+	rows      map[int][]interface{} // pending rows by pattern length
+	lats      map[int][]string      // the LAT file of each pending row
+	pending   map[string]int        // unwritten patterns by LAT file
+	finished  map[string]bool       // LAT files with all patterns added
+	committed func(lat string) error
+	count     int // patterns written
+	start     time.Time
+}
+
+func newBatcher(session neo4j.Session, size int, v scope) *batcher {
+	if size < 1 {
+		size = DefaultBatchSize
+	}
+	return &batcher{
+		session:  session,
+		size:     size,
+		merges:   memoBatchQuery(v),
+		version:  v,
+		rows:     make(map[int][]interface{}),
+		lats:     make(map[int][]string),
+		pending:  make(map[string]int),
+		finished: make(map[string]bool),
+		start:    time.Now(),
+	}
+}
+
+/**
+ * Queues a pattern, writing its batch once it is full.
+ */
+func (b *batcher) add(p dictionary.Pattern) error {
+	n := len(p.Words)
+	b.rows[n] = append(b.rows[n], patternParams(p.Lat, p.Words, b.version))
+	b.lats[n] = append(b.lats[n], p.Path)
+	b.pending[p.Path]++
+	if len(b.rows[n]) >= b.size {
+		return b.flush(n)
+	}
+	return nil
+}
+
+/**
+ * Marks that every pattern of the LAT file has been added, committing it
+ * now if they have all been written.
+ *
+ * @param lat: the Path of the LAT file.
+ */
+func (b *batcher) finish(lat string) error {
+	if b.pending[lat] > 0 {
+		b.finished[lat] = true
+		return nil
+	}
+	delete(b.pending, lat)
+	return b.commit(lat)
+}
+
+func (b *batcher) commit(lat string) error {
+	if b.committed == nil {
+		return nil
+	}
+	return b.committed(lat)
+}
+
+/**
+ * Writes the pending patterns with n words.
+ */
+func (b *batcher) flush(n int) error {
+	rows := b.rows[n]
+	if len(rows) == 0 {
+		return nil
+	}
+	_, txerr := b.session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		_, err := tx.Run(b.merges(n), map[string]interface{}{"rows": rows})
+		return nil, err
+	})
+	if errors.Is(txerr, errInterrupted) {
+		return txerr
+	} else if txerr != nil {
+		fmt.Printf("\nError on batch of %d patterns with %d words: %v\n", len(rows), n, txerr)
+		return txerr
+	}
+	lats := b.lats[n]
+	b.rows[n] = nil
+	b.lats[n] = nil
+	b.count += len(rows)
+	fmt.Printf("\r%d patterns, %.0f patterns/sec", b.count, b.rate())
+	for _, lat := range lats {
+		b.pending[lat]--
+		if b.pending[lat] == 0 && b.finished[lat] {
+			delete(b.pending, lat)
+			delete(b.finished, lat)
+			if err := b.commit(lat); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/**
+ * Writes all of the pending partial batches, shortest patterns first.
+ */
+func (b *batcher) flushAll() error {
+	lengths := make([]int, 0, len(b.rows))
+	for n := range b.rows {
+		lengths = append(lengths, n)
+	}
+	sort.Ints(lengths)
+	for _, n := range lengths {
+		if err := b.flush(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * Writes all of the remaining partial batches and reports the rate.
+ */
+func (b *batcher) close() error {
+	if err := b.flushAll(); err != nil {
+		return err
+	}
+	fmt.Printf("\rImported %d patterns in %v, %.0f patterns/sec.\n",
+		b.count, time.Since(b.start).Round(time.Second), b.rate())
+	return nil
+}
+
+func (b *batcher) rate() float64 {
+	elapsed := time.Since(b.start).Seconds()
+	if elapsed == 0 {
+		return 0
+	}
+	return float64(b.count) / elapsed
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+// DefaultCheckpoint is the checkpoint file for --resume without --checkpoint.
+const DefaultCheckpoint = "docuscope-rules-neo4j.checkpoint"
+
+// The import that a checkpoint file belongs to, its first line.
+type checkpointHeader struct {
+	Dictionary string `json:"dictionary"`
+	URI        string `json:"uri"`
+	Database   string `json:"database"`
+	Version    string `json:"version,omitempty"`
+}
+
+// A LAT file that has been completely imported, each following line.
+type checkpointEntry struct {
+	Lat    string `json:"lat"`
+	SHA256 string `json:"sha256"`
+}
+
+/**
+ * Records the LAT files that have been completely imported, one JSON line
+ * each, so that an interrupted import can be resumed.
+ */
+type checkpoint struct {
+	path string
+	file *os.File
+	done map[string]string // LAT file path to content hash
+}
+
+/**
+ * Opens the checkpoint file for an import.
+ *
+ * @param resume: keep the LAT files already recorded in the file, which
+ *   must be for the same import, instead of starting over.
+ */
+func openCheckpoint(path string, header checkpointHeader, resume bool) (*checkpoint, error) {
+	c := &checkpoint{path: path, done: make(map[string]string)}
+	if resume {
+		found, err := c.read(header)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			file, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, &dicterr.WriteError{Path: path, Err: err}
+			}
+			c.file = file
+			fmt.Printf("Resuming with %d LAT files already imported.\n", len(c.done))
+			return c, nil
+		}
+	}
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return nil, &dicterr.WriteError{Path: path, Err: err}
+	}
+	c.file = file
+	if err := c.append(header); err != nil {
+		c.file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+/**
+ * Reads the LAT files recorded in an existing checkpoint file.
+ *
+ * @return false if there is no checkpoint file.
+ */
+func (c *checkpoint) read(header checkpointHeader) (bool, error) {
+	file, err := os.Open(filepath.Clean(c.path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, &dicterr.ReadError{Path: c.path, Err: err}
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line == 1 {
+			var found checkpointHeader
+			if err := json.Unmarshal(scanner.Bytes(), &found); err != nil {
+				return false, &dicterr.FormatError{Path: c.path, Err: err}
+			}
+			if found != header {
+				return false, fmt.Errorf("%s is the checkpoint of another import, of %s into %s/%s, remove it or use another --checkpoint",
+					c.path, found.Dictionary, found.URI, found.Database)
+			}
+			continue
+		}
+		var entry checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line may be cut short by a crash.
+			continue
+		}
+		c.done[entry.Lat] = entry.SHA256
+	}
+	if err := scanner.Err(); err != nil {
+		return false, dicterr.Scan(c.path, line+1, err)
+	}
+	return line > 0, nil
+}
+
+/**
+ * Whether the LAT file has been imported with the same contents.
+ */
+func (c *checkpoint) finished(lat string, hash string) bool {
+	return c.done[lat] == hash
+}
+
+/**
+ * Records that the LAT file has been imported, syncing the checkpoint so
+ * that it survives a crash.
+ */
+func (c *checkpoint) record(lat string, hash string) error {
+	c.done[lat] = hash
+	return c.append(checkpointEntry{lat, hash})
+}
+
+func (c *checkpoint) append(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := c.file.Write(append(b, '\n')); err != nil {
+		return &dicterr.WriteError{Path: c.path, Err: err}
+	}
+	if err := c.file.Sync(); err != nil {
+		return &dicterr.WriteError{Path: c.path, Err: err}
+	}
+	return nil
+}
+
+func (c *checkpoint) Close() error {
+	return c.file.Close()
+}
+
+/**
+ * Removes the checkpoint once the import is complete.
+ */
+func (c *checkpoint) remove() error {
+	c.file.Close()
+	if err := os.Remove(c.path); err != nil {
+		return &dicterr.WriteError{Path: c.path, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golobby/dotenv"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+// DefaultEnvFile is read, if it exists, when no --env-file is given.
+const DefaultEnvFile = ".env"
+
+// Expected environment variables.
+type Env struct {
+	Neo4J struct {
+		Database string `env:"NEO4J_DATABASE"`
+		Uri      string `env:"NEO4J_URI"`
+		User     string `env:"NEO4J_USER"`
+		Pass     string `env:"NEO4J_PASSWORD"`
+	}
+}
+
+// Connection settings given on the command line.
+type settings struct {
+	uri          string
+	user         string
+	database     string
+	passwordFile string // file with the password or "-" for stdin
+	envFile      string // optional file of NEO4J_* variables
+}
+
+/**
+ * Reads the NEO4J_* variables from an env file.  A missing file is only an
+ * error if it was asked for.
+ */
+func readEnvFile(path string, required bool) (Env, error) {
+	config := Env{}
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+		return config, &dicterr.ReadError{Path: path, Err: err}
+	}
+	defer file.Close()
+
+	if err := dotenv.NewDecoder(file).Decode(&config); err != nil {
+		return config, &dicterr.FormatError{Path: path, Err: err}
+	}
+	return config, nil
+}
+
+/**
+ * Reads a password from the first line of a file, or of stdin if the
+ * path is "-".
+ */
+func readPassword(path string, stdin io.Reader) (string, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = ioutil.ReadAll(stdin)
+	} else {
+		b, err = ioutil.ReadFile(filepath.Clean(path))
+	}
+	if err != nil {
+		return "", &dicterr.ReadError{Path: path, Err: err}
+	}
+	return strings.TrimRight(strings.SplitN(string(b), "\n", 2)[0], "\r"), nil
+}
+
+/**
+ * Layers the connection settings: flags override environment variables,
+ * which override the env file.
+ *
+ * @param envFileSet: whether --env-file was given, making it an error if
+ *   the file is missing.
+ * @param lookup: looks up an environment variable, usually os.LookupEnv.
+ */
+func (s settings) resolve(envFileSet bool, lookup func(string) (string, bool), stdin io.Reader) (Env, error) {
+	config, err := readEnvFile(s.envFile, envFileSet)
+	if err != nil {
+		return config, err
+	}
+	layer := func(value *string, variable string, flag string) {
+		if v, found := lookup(variable); found && v != "" {
+			*value = v
+		}
+		if flag != "" {
+			*value = flag
+		}
+	}
+	layer(&config.Neo4J.Uri, "NEO4J_URI", s.uri)
+	layer(&config.Neo4J.User, "NEO4J_USER", s.user)
+	layer(&config.Neo4J.Database, "NEO4J_DATABASE", s.database)
+	layer(&config.Neo4J.Pass, "NEO4J_PASSWORD", "")
+	if s.passwordFile != "" {
+		config.Neo4J.Pass, err = readPassword(s.passwordFile, stdin)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	if config.Neo4J.Uri == "" {
+		return config, fmt.Errorf("no database URI: use --uri or set NEO4J_URI in the environment or %s", s.envFile)
+	}
+	return config, nil
+}
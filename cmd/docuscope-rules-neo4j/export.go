@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/output"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+// The CSV files written for neo4j-admin database import and their headers.
+var exportFiles = []struct {
+	name   string
+	header []string
+	flag   string
+}{
+	{"start_nodes.csv", []string{":ID", "word", ":LABEL"}, "--nodes"},
+	{"next_nodes.csv", []string{":ID"}, "--nodes"},
+	{"lat_nodes.csv", []string{":ID", "lat", ":LABEL"}, "--nodes"},
+	{"next_relationships.csv", []string{":START_ID", ":END_ID", "word", ":TYPE"}, "--relationships"},
+	{"lat_relationships.csv", []string{":START_ID", ":END_ID", ":TYPE"}, "--relationships"},
+	{"word_nodes.csv", []string{":ID", "word", ":LABEL"}, "--nodes"},
+	{"wordclass_nodes.csv", []string{":ID", "name", ":LABEL"}, "--nodes"},
+	{"in_class_relationships.csv", []string{":START_ID", ":END_ID", ":TYPE"}, "--relationships"},
+	{"cluster_nodes.csv", []string{":ID", "name", ":LABEL"}, "--nodes"},
+	{"dimension_nodes.csv", []string{":ID", "name", ":LABEL"}, "--nodes"},
+	{"has_dimension_relationships.csv", []string{":START_ID", ":END_ID", ":TYPE"}, "--relationships"},
+	{"has_lat_relationships.csv", []string{":START_ID", ":END_ID", ":TYPE"}, "--relationships"},
+	{"dictionary_nodes.csv", []string{":ID", "source", ":LABEL"}, "--nodes"},
+}
+
+const (
+	startNodes = iota
+	nextNodes
+	latNodes
+	nextRelationships
+	latRelationships
+	wordNodes
+	classNodes
+	inClassRelationships
+	clusterNodes
+	dimensionNodes
+	hasDimensionRelationships
+	hasLatRelationships
+	dictionaryNodes
+)
+
+// The node following another node by a :NEXT relationship with a word.
+type nextKey struct {
+	from int64
+	word string
+}
+
+// A :LAT relationship.
+type latKey struct {
+	from int64
+	lat  int64
+}
+
+/**
+ * Builds the same prefix tree of nodes as merging every pattern would and
+ * writes it as CSV rows, giving each node an id in the order it is first
+ * reached.
+ */
+type exporter struct {
+	writers  []*csv.Writer
+	starts   map[string]int64
+	nexts    map[nextKey]int64
+	lats     map[string]int64
+	latRels  map[latKey]bool
+	words    map[string]int64
+	classes  map[string]int64
+	clusters map[string]int64
+	dims     map[nextKey]int64 // dimensions by cluster and name
+	hasLats  map[latKey]bool
+	nextID   int64
+	patterns int
+	version  scope
+}
+
+func (e *exporter) write(file int, record ...string) {
+	// Errors are sticky and reported by the csv.Writer on Flush.
+	_ = e.writers[file].Write(record)
+}
+
+/**
+ * Writes a node, tagged with the version if there is one.
+ */
+func (e *exporter) node(file int, record ...string) {
+	if e.version != "" {
+		record = append(record, string(e.version))
+	}
+	e.write(file, record...)
+}
+
+func (e *exporter) id() string {
+	e.nextID++
+	return strconv.FormatInt(e.nextID, 10)
+}
+
+/**
+ * Adds the nodes and relationships of a pattern that are not already in
+ * the tree.
+ */
+func (e *exporter) add(p dictionary.Pattern) error {
+	if len(p.Words) == 0 {
+		return nil
+	}
+	e.patterns++
+	node, found := e.starts[p.Words[0]]
+	if !found {
+		e.node(startNodes, e.id(), p.Words[0], "Start")
+		node = e.nextID
+		e.starts[p.Words[0]] = node
+	}
+	for _, word := range p.Words[1:] {
+		key := nextKey{node, word}
+		next, found := e.nexts[key]
+		if !found {
+			e.node(nextNodes, e.id())
+			next = e.nextID
+			e.nexts[key] = next
+			e.write(nextRelationships, strconv.FormatInt(node, 10), strconv.FormatInt(next, 10), word, "NEXT")
+		}
+		node = next
+	}
+	lat, found := e.lats[p.Lat]
+	if !found {
+		e.node(latNodes, e.id(), p.Lat, "Lat")
+		lat = e.nextID
+		e.lats[p.Lat] = lat
+	}
+	if key := (latKey{node, lat}); !e.latRels[key] {
+		e.latRels[key] = true
+		e.write(latRelationships, strconv.FormatInt(node, 10), strconv.FormatInt(lat, 10), "LAT")
+	}
+	return nil
+}
+
+/**
+ * Adds a word's membership in a word class.
+ */
+func (e *exporter) addClass(word string, class string) {
+	w, found := e.words[word]
+	if !found {
+		e.node(wordNodes, e.id(), word, "Word")
+		w = e.nextID
+		e.words[word] = w
+	}
+	c, found := e.classes[class]
+	if !found {
+		e.node(classNodes, e.id(), class, "WordClass")
+		c = e.nextID
+		e.classes[class] = c
+	}
+	e.write(inClassRelationships, strconv.FormatInt(w, 10), strconv.FormatInt(c, 10), "IN_CLASS")
+}
+
+/**
+ * Adds a LAT's place in the tones hierarchy, only linking it if it has a
+ * :Lat node.
+ *
+ * @return false if the LAT has no patterns.
+ */
+func (e *exporter) addTone(cluster string, dimension string, lat string) bool {
+	c, found := e.clusters[cluster]
+	if !found {
+		e.node(clusterNodes, e.id(), cluster, "Cluster")
+		c = e.nextID
+		e.clusters[cluster] = c
+	}
+	key := nextKey{c, dimension}
+	dim, found := e.dims[key]
+	if !found {
+		e.node(dimensionNodes, e.id(), dimension, "Dimension")
+		dim = e.nextID
+		e.dims[key] = dim
+		e.write(hasDimensionRelationships, strconv.FormatInt(c, 10), strconv.FormatInt(dim, 10), "HAS_DIMENSION")
+	}
+	l, found := e.lats[lat]
+	if !found {
+		return false
+	}
+	if key := (latKey{dim, l}); !e.hasLats[key] {
+		e.hasLats[key] = true
+		e.write(hasLatRelationships, strconv.FormatInt(dim, 10), strconv.FormatInt(l, 10), "HAS_LAT")
+	}
+	return true
+}
+
+/**
+ * Writes the graph of the dictionary as node and relationship CSV files
+ * for neo4j-admin database import into the directory, which is created
+ * if needed.  The files for the tones are empty if t is nil.  The nodes
+ * of a version have a version column and the version has a :Dictionary
+ * node.
+ */
+func exportDictionary(d *dictionary.Dictionary, t tones.Tones, dir string, v scope) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &dicterr.WriteError{Path: dir, Err: err}
+	}
+	files := make([]*output.File, len(exportFiles))
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Abort()
+			}
+		}
+	}()
+	e := &exporter{
+		starts:   make(map[string]int64),
+		nexts:    make(map[nextKey]int64),
+		lats:     make(map[string]int64),
+		latRels:  make(map[latKey]bool),
+		words:    make(map[string]int64),
+		classes:  make(map[string]int64),
+		clusters: make(map[string]int64),
+		dims:     make(map[nextKey]int64),
+		hasLats:  make(map[latKey]bool),
+		version:  v,
+	}
+	for i, export := range exportFiles {
+		f, err := output.Create(filepath.Join(dir, export.name), output.None)
+		if err != nil {
+			return err
+		}
+		files[i] = f
+		e.writers = append(e.writers, csv.NewWriter(f))
+		header := export.header
+		if v != "" && export.flag == "--nodes" {
+			header = append(header[:len(header):len(header)], "version")
+		}
+		e.write(i, header...)
+	}
+
+	if err := d.Walk(e.add); err != nil {
+		return err
+	}
+	for _, row := range classMemberships(d, v) {
+		e.addClass(row["word"].(string), row["class"].(string))
+	}
+	missing := make(map[string]bool)
+	for _, row := range toneRows(t, v) {
+		if !e.addTone(row["cluster"].(string), row["dimension"].(string), row["lat"].(string)) {
+			missing[row["lat"].(string)] = true
+		}
+	}
+	if v != "" {
+		e.node(dictionaryNodes, e.id(), d.Directory, "Dictionary")
+	}
+
+	for i, w := range e.writers {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return &dicterr.WriteError{Path: files[i].Path, Err: err}
+		}
+		if err := files[i].Close(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d patterns as %d :Start, %d :NEXT, and %d :Lat nodes and %d :Word and %d :WordClass nodes.\n",
+		e.patterns, len(e.starts), len(e.nexts), len(e.lats), len(e.words), len(e.classes))
+	reportMissingLats(sortedKeys(missing))
+	return nil
+}
+
+/**
+ * Returns the neo4j-admin command that imports the exported files into
+ * the database.
+ */
+func importCommand(dir string, database string) string {
+	args := []string{"neo4j-admin", "database", "import", "full"}
+	for _, export := range exportFiles {
+		args = append(args, fmt.Sprintf("%s=%s", export.flag, filepath.Join(dir, export.name)))
+	}
+	return strings.Join(append(args, database), " ")
+}
+
+/**
+ * Exports the dictionary and shows how to import it and then create the
+ * indexes, which neo4j-admin does not.
+ */
+func exportCSV(directory string, opts options) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	t, err := dictionaryTones(d, opts.tones)
+	if err != nil {
+		return err
+	}
+	if err := exportDictionary(d, t, opts.export, opts.version); err != nil {
+		return err
+	}
+	if opts.stats {
+		fmt.Fprintln(os.Stderr, "Missing words:", d.DefaultWordsCount,
+			d.MissingWordsCount, len(d.Words))
+	}
+	fmt.Println("Import into a new, stopped, database with:")
+	fmt.Println(" ", importCommand(opts.export, "<database>"))
+	fmt.Println("Then create the indexes with:")
+	for _, index := range indexes(opts.version) {
+		fmt.Println(" ", index.query)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+// PatternSet is the set of patterns, words joined by spaces, of each LAT.
+type PatternSet map[string]map[string]bool
+
+func (s PatternSet) add(lat string, words []string) {
+	if s[lat] == nil {
+		s[lat] = make(map[string]bool)
+	}
+	s[lat][strings.Join(words, " ")] = true
+}
+
+/**
+ * Lists the patterns of each LAT that are in s but not in other, sorted.
+ */
+func (s PatternSet) minus(other PatternSet) map[string][]string {
+	result := make(map[string][]string)
+	for lat, patterns := range s {
+		for pattern := range patterns {
+			if !other[lat][pattern] {
+				result[lat] = append(result[lat], pattern)
+			}
+		}
+		sort.Strings(result[lat])
+	}
+	return result
+}
+
+/**
+ * Collects all of the patterns in the dictionary.
+ */
+func dictionaryPatterns(d *dictionary.Dictionary) (PatternSet, error) {
+	patterns := make(PatternSet)
+	err := d.Walk(func(p dictionary.Pattern) error {
+		patterns.add(p.Lat, p.Words)
+		return nil
+	})
+	return patterns, err
+}
+
+/**
+ * Returns the query that reads the patterns of up to $limit :Start nodes
+ * after the word $after, in order of their words, with a row for each
+ * pattern or a single row with a null lat for a :Start node without one.
+ */
+func graphPatternsQuery(v scope) string {
+	return fmt.Sprintf("MATCH (s:Start%s)%s WITH s ORDER BY s.word LIMIT $limit "+
+		"OPTIONAL MATCH p = (s)-[:NEXT*0..]->()-[:LAT]->(l:Lat) "+
+		"RETURN s.word AS first, [r IN relationships(p) WHERE type(r) = 'NEXT' | r.word] AS rest, l.lat AS lat;",
+		v.node("$"), v.where("s.word > $after", "s"))
+}
+
+/**
+ * Reads every pattern in the graph by following each :Start node along
+ * its :NEXT chains to the :LAT relationships, pageSize :Start nodes per
+ * transaction.
+ */
+func graphPatterns(session neo4j.Session, v scope, pageSize int) (PatternSet, error) {
+	if pageSize < 1 {
+		pageSize = DefaultBatchSize
+	}
+	patterns := make(PatternSet)
+	query := graphPatternsQuery(v)
+	after := ""
+	for {
+		starts, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+			result, err := tx.Run(query, map[string]interface{}{"version": string(v), "after": after, "limit": pageSize})
+			if err != nil {
+				return nil, err
+			}
+			starts := make(map[string]bool)
+			for result.Next() {
+				record := result.Record()
+				first, _ := record.Get("first")
+				rest, _ := record.Get("rest")
+				lat, _ := record.Get("lat")
+				starts[fmt.Sprint(first)] = true
+				if lat == nil {
+					continue
+				}
+				words := []string{fmt.Sprint(first)}
+				for _, w := range rest.([]interface{}) {
+					words = append(words, fmt.Sprint(w))
+				}
+				patterns.add(fmt.Sprint(lat), words)
+			}
+			return sortedKeys(starts), result.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+		words := starts.([]string)
+		if len(words) > 0 {
+			after = words[len(words)-1]
+		}
+		if len(words) < pageSize {
+			return patterns, nil
+		}
+	}
+}
+
+/**
+ * Returns the MATCH clause for the first n words of a pattern with the
+ * nodes named s0 to s<n-1> and the words as parameters <prefix>p0 to
+ * <prefix>p<n-1>.
+ *
+ * @param prefix: "$" for parameters or "row." for an UNWIND of $rows.
+ */
+func matchPath(n int, prefix string, v scope) string {
+	var qry strings.Builder
+	fmt.Fprintf(&qry, "MATCH (s0:Start {word: %sp0%s})", prefix, v.key(prefix))
+	for j := 1; j < n; j++ {
+		fmt.Fprintf(&qry, "-[:NEXT {word: %sp%d}]->(s%d)", prefix, j, j)
+	}
+	return qry.String()
+}
+
+/**
+ * Returns the query that removes the :LAT relationships of an UNWIND of
+ * $rows of patterns with n words.
+ */
+func deleteQuery(n int, v scope) string {
+	return fmt.Sprintf("UNWIND $rows AS row %s-[r:LAT]->(:Lat {lat: row.lat%s})%s DELETE r;",
+		matchPath(n, "row.", v), v.key("row."), v.where("", "s0"))
+}
+
+/**
+ * Returns the query that deletes the last node of each of an UNWIND of
+ * $rows of the first n words of patterns if nothing follows it any more.
+ */
+func pruneQuery(n int, v scope) string {
+	return fmt.Sprintf("UNWIND $rows AS row %s%s DETACH DELETE s%d;",
+		matchPath(n, "row.", v), v.where(fmt.Sprintf("NOT (s%d)-->()", n-1), "s0"), n-1)
+}
+
+/**
+ * Returns the query that deletes the :Lat nodes of an UNWIND of $rows once
+ * no pattern leads to them.
+ */
+func pruneLatQuery(v scope) string {
+	return fmt.Sprintf("UNWIND $rows AS row MATCH (l:Lat {lat: row.lat%s})%s DETACH DELETE l;",
+		v.key("row."), v.where("NOT ()-[:LAT]->(l)", "l"))
+}
+
+func patternParams(lat string, words []string, v scope) map[string]interface{} {
+	params := map[string]interface{}{"lat": lat, "version": string(v)}
+	for i, v := range words {
+		params[fmt.Sprint("p", i)] = v
+	}
+	return params
+}
+
+/**
+ * Deletes the removed patterns in batches of patterns with the same number
+ * of words, and then any of their :NEXT chains, from the end back to the
+ * :Start nodes, and their :Lat nodes that no longer lead to a LAT.
+ */
+func deletePatterns(session neo4j.Session, removed map[string][]string, batchSize int, v scope) error {
+	rows := make(map[int][]map[string]interface{}) // by pattern length
+	prefixes := make(map[int]map[string]bool)      // the prefixes of each length
+	var lats []map[string]interface{}
+	longest := 0
+	for _, lat := range sortedKeys(keySet(removed)) {
+		for _, pattern := range removed[lat] {
+			words := strings.Fields(pattern)
+			rows[len(words)] = append(rows[len(words)], patternParams(lat, words, v))
+			if len(words) > longest {
+				longest = len(words)
+			}
+			for n := 1; n <= len(words); n++ {
+				if prefixes[n] == nil {
+					prefixes[n] = make(map[string]bool)
+				}
+				prefixes[n][strings.Join(words[:n], " ")] = true
+			}
+		}
+		lats = append(lats, map[string]interface{}{"lat": lat, "version": string(v)})
+	}
+	for n := 1; n <= longest; n++ {
+		if err := writeRows(session, deleteQuery(n, v), rows[n], batchSize, "pattern"); err != nil {
+			return err
+		}
+	}
+	// The longest prefixes go first so that each node is pruned after the
+	// nodes that follow it.
+	for n := longest; n > 0; n-- {
+		var batch []map[string]interface{}
+		for _, prefix := range sortedKeys(prefixes[n]) {
+			batch = append(batch, patternParams("", strings.Fields(prefix), v))
+		}
+		if err := writeRows(session, pruneQuery(n, v), batch, batchSize, "pattern"); err != nil {
+			return err
+		}
+	}
+	return writeRows(session, pruneLatQuery(v), lats, batchSize, "LAT")
+}
+
+/**
+ * Brings the graph from the current patterns to those of the dictionary
+ * by merging added patterns and deleting removed patterns, with their
+ * orphaned :NEXT chains and :Lat nodes, in batches like a full import.
+ *
+ * @param current: the patterns in the graph or of the dictionary that was
+ *   previously imported.
+ */
+func updatePatterns(session neo4j.Session, d *dictionary.Dictionary, current PatternSet, batchSize int, v scope) error {
+	desired, err := dictionaryPatterns(d)
+	if err != nil {
+		return err
+	}
+	added := desired.minus(current)
+	removed := current.minus(desired)
+	lats := make(map[string]bool)
+	addedCount, removedCount := 0, 0
+	for lat, patterns := range added {
+		lats[lat] = true
+		addedCount += len(patterns)
+	}
+	for lat, patterns := range removed {
+		lats[lat] = true
+		removedCount += len(patterns)
+	}
+	fmt.Printf("%d patterns to add and %d to delete in %d LATs.\n", addedCount, removedCount, len(lats))
+
+	// Adding first keeps the shared parts of the chains from being pruned
+	// and merged again.
+	batches := newBatcher(session, batchSize, v)
+	for _, lat := range sortedKeys(keySet(added)) {
+		for _, pattern := range added[lat] {
+			if err := batches.add(dictionary.Pattern{Lat: lat, Words: strings.Fields(pattern)}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := batches.close(); err != nil {
+		return err
+	}
+	if err := deletePatterns(session, removed, batchSize, v); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d patterns.\n", removedCount)
+	return nil
+}
+
+// keySet returns the set of keys of m.
+func keySet(m map[string][]string) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/**
+ * Reads the patterns of the previously imported dictionary.
+ */
+func previousPatterns(path string) (PatternSet, error) {
+	d, err := dictionary.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	fmt.Fprintf(os.Stderr, "Comparing with the previous dictionary %s.\n", path)
+	return dictionaryPatterns(d)
+}
@@ -3,10 +3,52 @@ Tool for inserting DocuScope dictionary LAT rules to a neo4j graph database.
 
 The LAT rules in the database are of the form:
 (:Start {word: <word>}) -[:NEXT {word: <word>}]*-> () -[:LAT]->(:Lat {lat: <lat>})
+
+The word classes from _wordclasses.txt are of the form:
+(:Word {word: <word>}) -[:IN_CLASS]-> (:WordClass {name: <!CLASS>})
+so that the !CLASS tokens in :NEXT relationships can be resolved in the
+graph.
+
+With --tones the clusters and dimensions of _tones.txt are of the form:
+(:Cluster {name: <cluster>}) -[:HAS_DIMENSION]-> (:Dimension {name: <dimension>}) -[:HAS_LAT]-> (:Lat {lat: <lat>})
+linked only to the existing :Lat nodes.  The LATs in _tones.txt without any
+patterns are reported.
+
+With --dictionary-version <version> every node is tagged with a version
+property, and the indexes are composite indexes on it, so that several
+versions can be loaded side by side.  Each version has a
+(:Dictionary {version: <version>}) node.  The list-versions and drop-version
+commands list the loaded versions and delete one.
+
+With --checkpoint <file> a full import records each LAT file, with the
+SHA-256 of its contents, in the file once all of its patterns are merged.
+With --resume the LAT files already recorded with the same contents are
+skipped.
+Transactions that fail with transient errors are retried with exponential
+backoff, and an interrupt stops the import after the current transaction.
+
+Patterns are grouped by their number of words and merged --batch-size at a
+time with a single UNWIND $rows query for each batch.
+
+With --incremental, or --previous <dictionary>, only the patterns that were
+added or removed since the last import are merged or deleted.  Deleting a
+pattern also deletes the part of its :NEXT chain, and its :Lat node, that
+no longer leads to any LAT.
+
+The connection is configured by the --uri, --user, --database, and
+--password-file flags, which override the NEO4J_* environment variables,
+which override an optional env file (.env unless --env-file is given).
+
+With --export <directory>, no database is needed: the same graph is written
+as node and relationship CSV files for neo4j-admin database import so that
+a new database can be built offline.  With --dry-run <file> the import,
+with every parameter inlined as a literal, is written as a cypher-shell
+script instead.
 */
 /*
 Some performance metrics to show expected performance, in other words, this will take a while.
-DocuScope default dictionary 20210924:
+DocuScope default dictionary 20210924, with one query per pattern before
+patterns were merged in UNWIND batches:
 real	300m2.788s
 user	9m13.238s
 sys	7m44.774s
@@ -14,50 +56,29 @@ sys	7m44.774s
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"regexp"
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"time"
 
-	"github.com/golobby/dotenv"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/urfave/cli/v2"
-	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/fix"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
 	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
-	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/wordclasses"
 )
 
-// Expected environment variables.
-type Env struct {
-	Neo4J struct {
-		Database string `env:"NEO4J_DATABASE"`
-		Uri      string `env:"NEO4J_URI"`
-		User     string `env:"NEO4J_USER"`
-		Pass     string `env:"NEO4J_PASSWORD"`
-	}
-}
-
 func main() {
-	var flagStats bool
+	var opts options
+	var conn settings
+	var version string
 	var cpuprofile string
 	var memprofile string
 
-	config := Env{}
-	file, err := os.Open(".env")
-	if err != nil {
-		log.Fatal("Could not open .env: ", err)
-	}
-
-	err = dotenv.NewDecoder(file).Decode(&config)
-	if err != nil {
-		log.Fatal("Could not decode .env: ", err)
-	}
-
 	app := &cli.App{
 		Name:      "DocuScope Rules for Neo4j",
 		Usage:     "Populates a neo4j database with the rule and wordclasses.",
@@ -70,10 +91,88 @@ func main() {
 			},
 		},
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "uri",
+				Usage:       "Database `URI`, overrides NEO4J_URI",
+				Destination: &conn.uri,
+			},
+			&cli.StringFlag{
+				Name:        "user",
+				Usage:       "Database `username`, overrides NEO4J_USER",
+				Destination: &conn.user,
+			},
+			&cli.StringFlag{
+				Name:        "database",
+				Usage:       "Database `name`, overrides NEO4J_DATABASE",
+				Destination: &conn.database,
+			},
+			&cli.StringFlag{
+				Name:        "password-file",
+				Usage:       "Read the password from the first line of `file`, or stdin if -, instead of NEO4J_PASSWORD",
+				Destination: &conn.passwordFile,
+			},
+			&cli.StringFlag{
+				Name:        "env-file",
+				Value:       DefaultEnvFile,
+				Usage:       "Read NEO4J_* settings not in the environment from `file`, which is optional unless given",
+				Destination: &conn.envFile,
+			},
 			&cli.BoolFlag{
 				Name:        "stats",
 				Usage:       "Output statistics",
-				Destination: &flagStats,
+				Destination: &opts.stats,
+			},
+			&cli.IntFlag{
+				Name:        "batch-size",
+				Value:       DefaultBatchSize,
+				Usage:       "Number of patterns with the same length to merge in each `query`",
+				Destination: &opts.batchSize,
+			},
+			&cli.BoolFlag{
+				Name:        "tones",
+				Usage:       "Also load the clusters and dimensions of _tones.txt and link them to the LATs",
+				Destination: &opts.tones,
+			},
+			&cli.StringFlag{
+				Name:        "dictionary-version",
+				Usage:       "Tag every node with the dictionary `version` so that several versions can be loaded side by side",
+				Destination: &version,
+			},
+			&cli.StringFlag{
+				Name:        "checkpoint",
+				Usage:       "Record the LAT files that have been imported in `file`, which is removed once the import is complete, so that an interrupted import can be resumed",
+				Destination: &opts.checkpoint,
+			},
+			&cli.BoolFlag{
+				Name:        "resume",
+				Usage:       "Skip the LAT files recorded with the same contents in the --checkpoint file, " + DefaultCheckpoint + " if not given",
+				Destination: &opts.resume,
+			},
+			&cli.IntFlag{
+				Name:        "retries",
+				Value:       DefaultRetries,
+				Usage:       "Retry transactions that fail with transient errors up to `count` times with exponential backoff",
+				Destination: &opts.retries,
+			},
+			&cli.BoolFlag{
+				Name:        "incremental",
+				Usage:       "Compare with the patterns already in the graph and only add and delete the differences",
+				Destination: &opts.incremental,
+			},
+			&cli.StringFlag{
+				Name:        "previous",
+				Usage:       "Compare with the previously imported dictionary `path` instead of reading the graph, implies --incremental",
+				Destination: &opts.previous,
+			},
+			&cli.StringFlag{
+				Name:        "export",
+				Usage:       "Write node and relationship CSV files for neo4j-admin database import to `directory` instead of connecting to a database",
+				Destination: &opts.export,
+			},
+			&cli.StringFlag{
+				Name:        "dry-run",
+				Usage:       "Write the import as a cypher-shell script to `file`, or stdout if -, instead of connecting to a database",
+				Destination: &opts.dryRun,
 			},
 			&cli.StringFlag{
 				Name:        "cpuprofile",
@@ -88,11 +187,69 @@ func main() {
 				Destination: &memprofile,
 			},
 		},
+		Commands: []*cli.Command{
+			{
+				Name:      "list-versions",
+				Usage:     "List the dictionary versions loaded into the database",
+				UsageText: "docuscope-rules-neo4j list-versions",
+				Action: func(c *cli.Context) error {
+					config, err := conn.resolve(c.IsSet("env-file"), os.LookupEnv, os.Stdin)
+					if err != nil {
+						return err
+					}
+					driver, session, err := connect(config.Neo4J.Uri, config.Neo4J.User,
+						config.Neo4J.Pass, config.Neo4J.Database)
+					if err != nil {
+						return err
+					}
+					defer driver.Close()
+					defer session.Close()
+					return listVersions(session)
+				},
+			},
+			{
+				Name:      "drop-version",
+				Usage:     "Delete every node of a dictionary version from the database",
+				UsageText: "docuscope-rules-neo4j drop-version <version>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("drop-version takes exactly one version")
+					}
+					config, err := conn.resolve(c.IsSet("env-file"), os.LookupEnv, os.Stdin)
+					if err != nil {
+						return err
+					}
+					driver, session, err := connect(config.Neo4J.Uri, config.Neo4J.User,
+						config.Neo4J.Pass, config.Neo4J.Database)
+					if err != nil {
+						return err
+					}
+					defer driver.Close()
+					defer session.Close()
+					return dropVersion(session, scope(c.Args().First()), opts.batchSize)
+				},
+			},
+		},
 		Action: func(c *cli.Context) error {
-			return addDictionary(c.Args().First(),
+			opts.version = scope(version)
+			if opts.export != "" {
+				return exportCSV(c.Args().First(), opts)
+			}
+			if opts.dryRun != "" {
+				return dryRun(c.Args().First(), opts)
+			}
+			config, err := conn.resolve(c.IsSet("env-file"), os.LookupEnv, os.Stdin)
+			if err != nil {
+				return err
+			}
+			err = addDictionary(c.Args().First(),
 				config.Neo4J.Uri, config.Neo4J.User,
 				config.Neo4J.Pass, config.Neo4J.Database,
-				flagStats)
+				opts)
+			if errors.Is(err, errInterrupted) {
+				return cli.Exit("Interrupted.", 130)
+			}
+			return err
 		},
 	}
 	if cpuprofile != "" {
@@ -104,7 +261,7 @@ func main() {
 			log.Fatal("Could not start CPU profile: ", err)
 		}
 		if rerr := app.Run(os.Args); rerr != nil {
-			log.Fatal(rerr)
+			dicterr.Exit(rerr)
 		}
 
 		pprof.StopCPUProfile()
@@ -113,7 +270,7 @@ func main() {
 		}
 	} else {
 		if err := app.Run(os.Args); err != nil {
-			log.Fatal(err)
+			dicterr.Exit(err)
 		}
 	}
 
@@ -138,7 +295,7 @@ type MemoizedQuery func(int) string
  * Generates function that will return a query statement based on the number
  * of words in the LAT rule.
  */
-func memoQuery() MemoizedQuery {
+func memoQuery(v scope) MemoizedQuery {
 	cache := make(map[int]string)
 	cache[0] = ""
 	return func(index int) string {
@@ -146,129 +303,251 @@ func memoQuery() MemoizedQuery {
 			return val
 		}
 		//fmt.Printf("Generating query for %d\n", index)
-		var qry strings.Builder
-		qry.WriteString("MERGE (s0:Start {word: $p0}) ")
-		for j := 1; j < index; j++ {
-			fmt.Fprintf(&qry, "MERGE (s%d)-[:NEXT {word: $p%d}]->(s%d) ", j-1, j, j)
-		}
-		qry.WriteString("MERGE (l:Lat {lat: $lat}) ")
-		fmt.Fprintf(&qry, "MERGE (s%d)-[:LAT]->(l);", index-1)
-		result := qry.String()
+		result := mergeQuery(index, "$", v)
 		cache[index] = result
 		return result
 	}
 }
 
-func addDictionary(directory string, uri string, username string, password string, database string, flagStats bool) error {
+/**
+ * Builds the MERGE statements for a LAT rule with the given number of
+ * words, reading the words p0 to p<n-1> and the lat from parameters with
+ * the given prefix, either "$" or an UNWIND variable such as "row.".  The
+ * nodes are tagged with the version of the scope.
+ */
+func mergeQuery(index int, prefix string, v scope) string {
+	var qry strings.Builder
+	fmt.Fprintf(&qry, "MERGE (s0:Start {word: %sp0%s}) ", prefix, v.key(prefix))
+	for j := 1; j < index; j++ {
+		fmt.Fprintf(&qry, "MERGE (s%d)-[:NEXT {word: %sp%d}]->(s%d%s) ", j-1, prefix, j, j, v.node(prefix))
+	}
+	fmt.Fprintf(&qry, "MERGE (l:Lat {lat: %slat%s}) ", prefix, v.key(prefix))
+	fmt.Fprintf(&qry, "MERGE (s%d)-[:LAT]->(l);", index-1)
+	return qry.String()
+}
+
+// An index creation statement.
+type index struct {
+	name  string
+	query string
+}
+
+// The node keys that are indexed, as label, variable, and property.
+var indexedKeys = [][3]string{
+	{"Start", "s", "word"},
+	{"Lat", "l", "lat"},
+	{"Word", "w", "word"},
+	{"WordClass", "c", "name"},
+	{"Cluster", "c", "name"},
+	{"Dimension", "d", "name"},
+}
+
+/**
+ * Returns the indexes used by the tagger to follow the patterns and look
+ * up the word classes and tones.  Both modes index the same keys; for a
+ * version, the node indexes are composite indexes on the version and the
+ * key, and the :Dictionary nodes of the versions are indexed as well.
+ */
+func indexes(v scope) []index {
+	var result []index
+	for _, key := range indexedKeys {
+		label, variable, property := key[0], key[1], key[2]
+		name := strings.ToLower(label) + "_index"
+		on := variable + "." + property
+		if v != "" {
+			name = strings.ToLower(label) + "_version_index"
+			on = variable + ".version, " + on
+		}
+		result = append(result, index{name,
+			fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR (%s:%s) ON (%s);", name, variable, label, on)})
+	}
+	result = append(result, index{"next_index", "CREATE INDEX next_index IF NOT EXISTS FOR ()-[n:NEXT]->() ON (n.word);"})
+	if v != "" {
+		result = append(result, index{"dictionary_version_index",
+			"CREATE INDEX dictionary_version_index IF NOT EXISTS FOR (d:Dictionary) ON (d.version);"})
+	}
+	return result
+}
+
+/**
+ * Opens a write session on the database.  Both must be closed.
+ */
+func connect(uri string, username string, password string, database string) (neo4j.Driver, neo4j.Session, error) {
 	fmt.Printf("Connecting to %q/%q as %q.\n", uri, database, username)
 	driver, err := neo4j.NewDriver(uri, neo4j.BasicAuth(username, password, ""))
 	if err != nil {
-		log.Fatal("Could not open database: ", uri, username, err)
-		panic(err)
+		return nil, nil, fmt.Errorf("could not open database %q as %q: %w", uri, username, err)
 	}
-	defer driver.Close()
-
 	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite, DatabaseName: database})
-	defer session.Close()
+	return driver, session, nil
+}
+
+// Options for the import.
+type options struct {
+	stats       bool
+	batchSize   int    // patterns in each UNWIND query
+	incremental bool   // only add and delete the patterns that changed
+	previous    string // previously imported dictionary to compare with
+	export      string // directory for neo4j-admin import CSV files
+	dryRun      string // file for a cypher-shell script of the import
+	tones       bool   // also load the _tones.txt hierarchy
+	version     scope  // dictionary version to tag the nodes with
+	checkpoint  string // file recording the LAT files imported, if any
+	resume      bool   // skip the LAT files in the checkpoint
+	retries     int    // retries of transactions with transient errors
+}
+
+func addDictionary(directory string, uri string, username string, password string, database string, opts options) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	t, err := dictionaryTones(d, opts.tones)
+	if err != nil {
+		return err
+	}
+
+	driver, conn, err := connect(uri, username, password, database)
+	if err != nil {
+		return err
+	}
+	defer driver.Close()
+	defer conn.Close()
+	interrupt := &interruption{}
+	defer interrupt.watch()()
+	session := &resilientSession{Session: conn, retries: opts.retries, delay: time.Second, interrupt: interrupt}
+	if opts.version == "" {
+		if err := checkUnversioned(session); err != nil {
+			return err
+		}
+	}
 	// Create index
 	_, txerr := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
-		_, err := tx.Run("CREATE INDEX start_index IF NOT EXISTS FOR (s:Start) ON (s.word);", map[string]interface{}{})
-		if err != nil {
-			fmt.Printf("Error on start_index: %v.\n", err)
-			return nil, err
-		}
-		_, err1 := tx.Run("CREATE INDEX lat_index IF NOT EXISTS FOR (l:Lat) ON (l.lat);", map[string]interface{}{})
-		if err1 != nil {
-			fmt.Printf("Error on lat_index: %v.\n", err1)
-			return nil, err1
-		}
-		_, err2 := tx.Run("CREATE INDEX next_index IF NOT EXISTS FOR ()-[n:NEXT]->() ON (n.word);", map[string]interface{}{})
-		if err2 != nil {
-			fmt.Printf("Error on next_index: %v\n", err2)
+		for _, index := range indexes(opts.version) {
+			_, err := tx.Run(index.query, map[string]interface{}{})
+			if err != nil {
+				fmt.Printf("Error on %s: %v.\n", index.name, err)
+				return nil, err
+			}
 		}
-		return nil, err2
+		return nil, nil
 	})
 	if txerr != nil {
 		fmt.Printf("Error on index transaction: %v\n", txerr)
-		panic(txerr)
+		return txerr
 	}
-	// Start memoized query provider.
-	merges := memoQuery()
-
-	words := make(map[string][]string)
-	defaultWordsCount := 0
-	missingWordsCount := 0
-	//ruleCount := 0
-	wordclasses.ReadWords(words, filepath.Join(directory, "_wordclasses.txt"))
-	defaultWordsCount = len(words)
-	patternRe := regexp.MustCompile(`[!?\w'-]+|[!"#$%&'()*+,-./:;<=>?@[\]^_\` + "`" + `{|}~]`)
-	walkerr := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+	var cp *checkpoint
+	if opts.previous != "" {
+		current, err := previousPatterns(opts.previous)
 		if err != nil {
-			fmt.Printf("Error: unable to access %q: %v\n", path, err)
-			panic(err)
+			return err
 		}
-		base := filepath.Base(path)
-		if !info.IsDir() && filepath.Ext(path) == ".txt" &&
-			!strings.HasPrefix(base, "_") {
-			lat := strings.TrimSuffix(base, ".txt")
-			content, oerr := os.Open(filepath.Clean(path))
-			if oerr != nil {
-				fmt.Printf("Error: unable to access %q: %v\n", path, oerr)
-				panic(oerr)
-			}
-			numPatterns := 0
-			scanner := bufio.NewScanner(content)
-			_, txerr := session.WriteTransaction(func(transaction neo4j.Transaction) (interface{}, error) {
-				for scanner.Scan() {
-					pattern := fix.Case(patternRe.FindAllString(scanner.Text(), -1))
-					if len(pattern) > 0 {
-						var pmap = map[string]interface{}{
-							"lat": lat,
-						}
-						for i, v := range pattern {
-							pmap[fmt.Sprint("p", i)] = v
-						}
-						numPatterns++
-						if numPatterns%1000 == 0 {
-							fmt.Printf("\r%d", numPatterns)
-						}
-						_, err := transaction.Run(
-							merges(len(pattern)),
-							pmap)
-						// add length binning count
-						if err != nil {
-							fmt.Printf("Query error: %q %d %v\n", lat, len(pattern), pattern)
-							fmt.Printf("Query: %q %v\n", merges(len(pattern)), pmap)
-							return nil, err
-						}
-					}
-					for _, w := range pattern {
-						if wds, ok := words[w]; !ok {
-							words[w] = append(wds, w)
-							missingWordsCount++
-						}
-					}
-				}
-				return nil, nil
-			})
-			if txerr != nil {
-				fmt.Printf("Error on transaction: %q: %v\n", lat, txerr)
-				panic(txerr)
+		err = updatePatterns(session, d, current, opts.batchSize, opts.version)
+		if err != nil {
+			return err
+		}
+	} else if opts.incremental {
+		fmt.Println("Reading the patterns in the graph.")
+		current, err := graphPatterns(session, opts.version, opts.batchSize)
+		if err != nil {
+			return fmt.Errorf("could not read the patterns in the graph: %w", err)
+		}
+		err = updatePatterns(session, d, current, opts.batchSize, opts.version)
+		if err != nil {
+			return err
+		}
+	} else {
+		path := opts.checkpoint
+		if path == "" && opts.resume {
+			path = DefaultCheckpoint
+		}
+		if path != "" {
+			header := checkpointHeader{d.Directory, uri, database, string(opts.version)}
+			if cp, err = openCheckpoint(path, header, opts.resume); err != nil {
+				return err
 			}
-			if err := content.Close(); err != nil {
-				log.Fatal("Could not close content file: ", err)
-				panic(err)
+			defer cp.Close()
+		}
+		if err := importPatterns(session, d, opts.batchSize, opts.version, cp); err != nil {
+			if errors.Is(err, errInterrupted) && cp != nil {
+				return cli.Exit(fmt.Sprintf("Interrupted, run again with --resume to continue from %s.", cp.path), 130)
+			} else if errors.Is(err, errInterrupted) {
+				return cli.Exit("Interrupted, use --checkpoint to be able to resume an import.", 130)
 			}
-			fmt.Printf("\r%q %d\n", lat, numPatterns) //, time
+			return err
+		}
+	}
+	err = writeWordClasses(session, d, opts.batchSize, opts.version, opts.incremental || opts.previous != "")
+	if err != nil {
+		return err
+	}
+	if t != nil {
+		err = writeTones(session, t, opts.batchSize, opts.version, opts.incremental || opts.previous != "")
+		if err != nil {
+			return err
 		}
-		return nil
-	})
-	if walkerr != nil {
-		panic(walkerr)
 	}
-	if flagStats {
-		fmt.Fprintln(os.Stderr, "Missing words:", defaultWordsCount,
-			missingWordsCount, len(words))
+	if opts.version != "" {
+		if err := registerVersion(session, opts.version, d.Directory); err != nil {
+			return fmt.Errorf("could not record version %q: %w", opts.version, err)
+		}
+	}
+	if cp != nil {
+		// The import is complete.
+		if err := cp.remove(); err != nil {
+			return err
+		}
+	}
+	if opts.stats {
+		fmt.Fprintln(os.Stderr, "Missing words:", d.DefaultWordsCount,
+			d.MissingWordsCount, len(d.Words))
 	}
 	return nil
 }
+
+/**
+ * Merges every pattern of the dictionary into the graph in batches of
+ * patterns with the same number of words, across LAT files.  A LAT file is
+ * recorded in the checkpoint, if any, once all of its patterns have been
+ * written, and LAT files already recorded with the same contents are
+ * skipped.
+ * Skipped LAT files are still walked, without writing, so that the word
+ * statistics of the dictionary are complete.
+ */
+func importPatterns(session neo4j.Session, d *dictionary.Dictionary, batchSize int, v scope, cp *checkpoint) error {
+	batches := newBatcher(session, batchSize, v)
+	hashes := make(map[string]string)
+	if cp != nil {
+		batches.committed = func(lat string) error {
+			return cp.record(lat, hashes[lat])
+		}
+	}
+	skipped := 0
+	for _, lat := range d.Lats {
+		if cp != nil {
+			hash, err := d.HashLat(lat)
+			if err != nil {
+				return err
+			}
+			if cp.finished(lat.Path, hash) {
+				skipped++
+				if err := d.WalkLat(lat, func(dictionary.Pattern) error { return nil }); err != nil {
+					return err
+				}
+				continue
+			}
+			hashes[lat.Path] = hash
+		}
+		if err := d.WalkLat(lat, batches.add); err != nil {
+			return err
+		}
+		if err := batches.finish(lat.Path); err != nil {
+			return err
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %d LAT files that were already imported.\n", skipped)
+	}
+	return batches.close()
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+const testDictionary = "../../testdata/dictionary"
+
+func TestMemoQuery(t *testing.T) {
+	merges := memoQuery("")
+	var actual strings.Builder
+	for i := 1; i <= 4; i++ {
+		fmt.Fprintf(&actual, "%d: %s\n", i, merges(i))
+	}
+	golden.Assert(t, filepath.Join("testdata", "memo-query.golden"), []byte(actual.String()))
+
+	if merges(3) != merges(3) {
+		t.Errorf("Expected the memoized query to be stable!")
+	}
+	if merges(0) != "" {
+		t.Errorf("Expected no query for an empty pattern but instead got %q!", merges(0))
+	}
+}
+
+func TestPatternSetMinus(t *testing.T) {
+	old := make(PatternSet)
+	old.add("Confidence", []string{"certainly"})
+	old.add("Confidence", []string{"without", "doubt"})
+	old.add("Uncertainty", []string{"maybe"})
+	next := make(PatternSet)
+	next.add("Confidence", []string{"certainly"})
+	next.add("Confidence", []string{"no", "doubt"})
+
+	added := next.minus(old)
+	if len(added) != 1 || strings.Join(added["Confidence"], "|") != "no doubt" {
+		t.Errorf("Unexpected added patterns: %v", added)
+	}
+	removed := old.minus(next)
+	if strings.Join(removed["Confidence"], "|") != "without doubt" ||
+		strings.Join(removed["Uncertainty"], "|") != "maybe" {
+		t.Errorf("Unexpected removed patterns: %v", removed)
+	}
+}
+
+func TestDeleteQueries(t *testing.T) {
+	var actual strings.Builder
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(&actual, "delete %d: %s\n", i, deleteQuery(i, ""))
+		fmt.Fprintf(&actual, "prune %d: %s\n", i, pruneQuery(i, ""))
+	}
+	fmt.Fprintf(&actual, "prune lat: %s\n", pruneLatQuery(""))
+	fmt.Fprintf(&actual, "delete 2 version: %s\n", deleteQuery(2, "v2"))
+	fmt.Fprintf(&actual, "prune 2 version: %s\n", pruneQuery(2, "v2"))
+	fmt.Fprintf(&actual, "prune lat version: %s\n", pruneLatQuery("v2"))
+	fmt.Fprintf(&actual, "patterns: %s\n", graphPatternsQuery(""))
+	fmt.Fprintf(&actual, "patterns version: %s\n", graphPatternsQuery("v2"))
+	golden.Assert(t, filepath.Join("testdata", "delete-query.golden"), []byte(actual.String()))
+}
+
+func TestPruneQueries(t *testing.T) {
+	var actual strings.Builder
+	for _, v := range []scope{"", "v2"} {
+		fmt.Fprintf(&actual, "version %q\n", v)
+		fmt.Fprintf(&actual, "memberships: %s\n", graphMembershipsQuery(v))
+		fmt.Fprintf(&actual, "delete memberships: %s\n", deleteMembershipsQuery(v))
+		for _, query := range pruneWordClassesQuery(v) {
+			fmt.Fprintf(&actual, "prune word classes: %s\n", query)
+		}
+		for _, query := range pruneTonesQuery(v) {
+			fmt.Fprintf(&actual, "prune tones: %s\n", query)
+		}
+	}
+	golden.Assert(t, filepath.Join("testdata", "prune-query.golden"), []byte(actual.String()))
+}
+
+func TestPruneTonesParamsEmpty(t *testing.T) {
+	params := pruneTonesParams(nil, "")
+	for _, key := range []string{"tones", "dimensions", "clusters"} {
+		if list, ok := params[key].([]interface{}); !ok || list == nil || len(list) != 0 {
+			t.Errorf("Expected $%s to be an empty list but instead got %#v!", key, params[key])
+		}
+	}
+}
+
+func TestDropQueries(t *testing.T) {
+	var actual strings.Builder
+	for _, label := range append([]string{"Start"}, versionedLabels...) {
+		count, drop := dropQueries(label, 500)
+		fmt.Fprintf(&actual, "count %s: %s\ndrop %s: %s\n", label, count, label, drop)
+	}
+	golden.Assert(t, filepath.Join("testdata", "drop-query.golden"), []byte(actual.String()))
+}
+
+func TestMemoBatchQuery(t *testing.T) {
+	merges := memoBatchQuery("")
+	var actual strings.Builder
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(&actual, "%d: %s\n", i, merges(i))
+	}
+	golden.Assert(t, filepath.Join("testdata", "batch-query.golden"), []byte(actual.String()))
+}
+
+func TestExportCSV(t *testing.T) {
+	dir := t.TempDir()
+	stdout, err := golden.Stdout(t, func() error {
+		return exportCSV(testDictionary, options{export: dir, tones: true})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual strings.Builder
+	for _, export := range exportFiles {
+		b, err := ioutil.ReadFile(filepath.Join(dir, export.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&actual, "== %s\n%s", export.name, b)
+	}
+	golden.Assert(t, filepath.Join("testdata", "export.golden"), []byte(actual.String()))
+
+	if !strings.Contains(string(stdout), importCommand(dir, "<database>")) {
+		t.Errorf("Expected the neo4j-admin command in the output but got:\n%s", stdout)
+	}
+}
+
+func TestResolveSettings(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "test.env")
+	err := ioutil.WriteFile(envFile, []byte(
+		"NEO4J_URI=bolt://file:7687\nNEO4J_USER=fileuser\nNEO4J_DATABASE=filedb\nNEO4J_PASSWORD=filepass\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"NEO4J_USER": "envuser", "NEO4J_PASSWORD": "envpass"}
+	lookup := func(name string) (string, bool) {
+		v, found := env[name]
+		return v, found
+	}
+
+	s := settings{database: "flagdb", envFile: envFile}
+	config, err := s.resolve(true, lookup, strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Neo4J.Uri != "bolt://file:7687" || config.Neo4J.User != "envuser" ||
+		config.Neo4J.Database != "flagdb" || config.Neo4J.Pass != "envpass" {
+		t.Errorf("Unexpected settings: %+v", config.Neo4J)
+	}
+
+	s = settings{uri: "bolt://flag:7687", passwordFile: "-", envFile: envFile}
+	config, err = s.resolve(true, lookup, strings.NewReader("stdinpass\r\nignored\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Neo4J.Uri != "bolt://flag:7687" || config.Neo4J.Pass != "stdinpass" {
+		t.Errorf("Unexpected settings: %+v", config.Neo4J)
+	}
+
+	missing := filepath.Join(t.TempDir(), ".env")
+	s = settings{uri: "bolt://flag:7687", envFile: missing}
+	if _, err := s.resolve(false, lookup, nil); err != nil {
+		t.Errorf("Expected the default env file to be optional but got %v", err)
+	}
+	if _, err := s.resolve(true, lookup, nil); err == nil {
+		t.Errorf("Expected an error for a missing --env-file")
+	}
+	s = settings{envFile: missing}
+	if _, err := s.resolve(false, lookup, nil); err == nil {
+		t.Errorf("Expected an error without a URI")
+	}
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		query    string
+		params   map[string]interface{}
+		expected string
+	}{
+		{"MERGE (s0:Start {word: $p0})", map[string]interface{}{"p0": "it's"}, `MERGE (s0:Start {word: 'it\'s'})`},
+		{"RETURN $p1, $p10", map[string]interface{}{"p1": `a\b`, "p10": 3}, `RETURN 'a\\b', 3`},
+		{"RETURN $lat", map[string]interface{}{"lat": "line\nbreak"}, `RETURN 'line\nbreak'`},
+	}
+	for _, test := range tests {
+		actual, err := inline(test.query, test.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != test.expected {
+			t.Errorf("Expected %s but got %s", test.expected, actual)
+		}
+	}
+	if _, err := inline("RETURN $missing", map[string]interface{}{}); err == nil {
+		t.Errorf("Expected an error for a missing parameter")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   options
+	}{
+		{"dry-run.golden", options{dryRun: "-", tones: true}},
+		{"dry-run-version.golden", options{dryRun: "-", tones: true, version: "v2"}},
+	}
+	for _, test := range tests {
+		actual, err := golden.Stdout(t, func() error {
+			return dryRun(testDictionary, test.opts)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, filepath.Join("testdata", test.golden), actual)
+	}
+}
+
+func TestMissingLats(t *testing.T) {
+	rows := toneRows(tones.Tones{
+		"Other":      {"Excited": {"Exclamation", "Shouting"}},
+		"Confidence": {"Uncertain": {"Uncertainty"}, "Certain": {"Confidence", "Shouting"}},
+	}, "")
+	var actual []string
+	for _, row := range rows {
+		actual = append(actual, fmt.Sprint(row["cluster"], "/", row["dimension"], "/", row["lat"]))
+	}
+	expected := "Confidence/Certain/Confidence Confidence/Certain/Shouting Confidence/Uncertain/Uncertainty Other/Excited/Exclamation Other/Excited/Shouting"
+	if strings.Join(actual, " ") != expected {
+		t.Errorf("Expected rows %s but got %s", expected, strings.Join(actual, " "))
+	}
+
+	missing := missingLats(rows, func(lat string) bool { return lat != "Shouting" })
+	if strings.Join(missing, " ") != "Shouting" {
+		t.Errorf("Expected only Shouting to be missing but got %v", missing)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.checkpoint")
+	header := checkpointHeader{Dictionary: "dictionary", URI: "bolt://localhost:7687", Database: "neo4j"}
+	cp, err := openCheckpoint(path, header, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.record("dictionary/Confidence.txt", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err = openCheckpoint(path, header, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cp.finished("dictionary/Confidence.txt", "abc") {
+		t.Errorf("Expected the recorded LAT file to be finished")
+	}
+	if cp.finished("dictionary/Confidence.txt", "changed") || cp.finished("dictionary/Hedges.txt", "abc") {
+		t.Errorf("Expected changed and unrecorded LAT files not to be finished")
+	}
+	if err := cp.remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err = openCheckpoint(path, header, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	other := header
+	other.Version = "v2"
+	if _, err := openCheckpoint(path, other, true); err == nil {
+		t.Errorf("Expected an error resuming from the checkpoint of another import")
+	}
+}
+
+// fakeSession commits every write transaction without running it.
+type fakeSession struct {
+	neo4j.Session
+	writes int
+}
+
+func (s *fakeSession) WriteTransaction(neo4j.TransactionWork, ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	s.writes++
+	return nil, nil
+}
+
+func TestBatcherCommitted(t *testing.T) {
+	session := &fakeSession{}
+	batches := newBatcher(session, 2, "")
+	var committed []string
+	batches.committed = func(lat string) error {
+		committed = append(committed, lat)
+		return nil
+	}
+	add := func(lat string, words ...string) func() error {
+		return func() error {
+			return batches.add(dictionary.Pattern{Lat: lat, Words: words, Path: lat + ".txt"})
+		}
+	}
+	finish := func(lat string) func() error {
+		return func() error { return batches.finish(lat + ".txt") }
+	}
+	steps := []func() error{
+		add("A", "a"),
+		add("A", "a", "b"),
+		finish("A"),
+		// Writes the one word batch, leaving A with a pattern in the two word batch.
+		add("B", "b"),
+		finish("B"),
+		add("C", "c", "d"),
+		finish("C"),
+		finish("D"),
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := []string{"B.txt", "A.txt", "C.txt", "D.txt"}; !reflect.DeepEqual(committed, expected) {
+		t.Errorf("Expected the LAT files to be committed in the order %v but instead got %v!", expected, committed)
+	}
+	if session.writes != 2 {
+		t.Errorf("Expected 2 batches to be written but instead got %d!", session.writes)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	attempts := 0
+	_, err := withRetry(3, time.Millisecond, func() (interface{}, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, &neo4j.ConnectivityError{Inner: errors.New("connection reset")}
+		}
+		return nil, nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success on the third attempt but got %v after %d", err, attempts)
+	}
+
+	attempts = 0
+	_, err = withRetry(3, time.Millisecond, func() (interface{}, error) {
+		attempts++
+		return nil, errors.New("syntax error")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected no retry of a permanent error but got %v after %d", err, attempts)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// DefaultRetries is the number of times a failed transaction is retried.
+const DefaultRetries = 5
+
+// errInterrupted is returned instead of starting a transaction after an
+// interrupt.
+var errInterrupted = errors.New("interrupted")
+
+/**
+ * Whether an error is one that may go away if the transaction is retried,
+ * such as a lost connection, a deadlock, or the driver running out of its
+ * own retry time.
+ */
+func transient(err error) bool {
+	var connectivity *neo4j.ConnectivityError
+	var limit *neo4j.TransactionExecutionLimit
+	return neo4j.IsRetryable(err) || errors.As(err, &connectivity) || errors.As(err, &limit)
+}
+
+/**
+ * Calls work until it succeeds, fails with an error that is not transient,
+ * or has been retried the given number of times, doubling the delay
+ * between attempts.
+ */
+func withRetry(retries int, delay time.Duration, work func() (interface{}, error)) (interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := work()
+		if err == nil || attempt >= retries || !transient(err) {
+			return result, err
+		}
+		fmt.Printf("\nTransaction failed, retrying in %v: %v\n", delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+/**
+ * Set once an interrupt has been received.
+ */
+type interruption struct {
+	flag int32
+}
+
+func (i *interruption) requested() bool {
+	return atomic.LoadInt32(&i.flag) != 0
+}
+
+/**
+ * Watches for the first SIGINT to ask the import to stop after the current
+ * transaction.  A second SIGINT exits immediately.
+ *
+ * @return a function that stops watching.
+ */
+func (i *interruption) watch() func() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		for range signals {
+			if atomic.SwapInt32(&i.flag, 1) != 0 {
+				fmt.Fprintln(os.Stderr, "\nInterrupted.")
+				os.Exit(130)
+			}
+			fmt.Fprintln(os.Stderr, "\nFinishing the current transaction, interrupt again to quit now.")
+		}
+	}()
+	return func() { signal.Stop(signals) }
+}
+
+/**
+ * A session that retries transactions that fail with transient errors,
+ * beyond the driver's own retries, and does not start a new transaction
+ * once interrupted.
+ */
+type resilientSession struct {
+	neo4j.Session
+	retries   int
+	delay     time.Duration
+	interrupt *interruption
+}
+
+func (s *resilientSession) WriteTransaction(work neo4j.TransactionWork, configurers ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	if s.interrupt.requested() {
+		return nil, errInterrupted
+	}
+	return withRetry(s.retries, s.delay, func() (interface{}, error) {
+		return s.Session.WriteTransaction(work, configurers...)
+	})
+}
+
+func (s *resilientSession) ReadTransaction(work neo4j.TransactionWork, configurers ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	if s.interrupt.requested() {
+		return nil, errInterrupted
+	}
+	return withRetry(s.retries, s.delay, func() (interface{}, error) {
+		return s.Session.ReadTransaction(work, configurers...)
+	})
+}
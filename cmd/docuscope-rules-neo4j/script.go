@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/output"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+var parameter = regexp.MustCompile(`\$[A-Za-z_][A-Za-z0-9_]*`)
+
+var cypherEscapes = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+/**
+ * Returns a value as a Cypher literal.
+ */
+func literal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return "'" + cypherEscapes.Replace(v) + "'", nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	default:
+		return "", fmt.Errorf("no Cypher literal for %T", value)
+	}
+}
+
+/**
+ * Replaces the $parameters of a query with their values as literals.
+ */
+func inline(query string, params map[string]interface{}) (string, error) {
+	var err error
+	result := parameter.ReplaceAllStringFunc(query, func(name string) string {
+		value, found := params[name[1:]]
+		if !found {
+			err = fmt.Errorf("no value for %s in %q", name, query)
+			return name
+		}
+		lit, lerr := literal(value)
+		if lerr != nil {
+			err = lerr
+		}
+		return lit
+	})
+	return result, err
+}
+
+/**
+ * Writes the whole import, the indexes followed by the MERGE of every
+ * pattern with one transaction for each LAT and then of the word classes
+ * and, unless t is nil, the tones, as a cypher-shell script.  A version is
+ * recorded at the end.
+ *
+ * @param path: the .cypher file, compressed according to its extension,
+ *   or "-" for stdout.
+ */
+func writeScript(d *dictionary.Dictionary, t tones.Tones, path string, v scope) error {
+	out, err := output.Create(path, output.Auto)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+	// Write errors are sticky and reported by Flush.
+	w := bufio.NewWriter(out)
+
+	for _, index := range indexes(v) {
+		fmt.Fprintln(w, index.query)
+	}
+	merges := memoQuery(v)
+	count := 0
+	patternLats := make(map[string]bool)
+	for _, lat := range d.Lats {
+		fmt.Fprintln(w, ":begin")
+		err := d.WalkLat(lat, func(p dictionary.Pattern) error {
+			statement, err := inline(merges(len(p.Words)), patternParams(p.Lat, p.Words, v))
+			if err != nil {
+				return err
+			}
+			count++
+			patternLats[p.Lat] = true
+			fmt.Fprintln(w, statement)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, ":commit")
+	}
+	fmt.Fprintln(w, ":begin")
+	for _, row := range classMemberships(d, v) {
+		statement, err := inline(wordClassQuery("$", v), row)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, statement)
+	}
+	fmt.Fprintln(w, ":commit")
+	var missing []string
+	if t != nil {
+		rows := toneRows(t, v)
+		fmt.Fprintln(w, ":begin")
+		for _, row := range rows {
+			statement, err := inline(toneQuery("$", v), row)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, statement)
+		}
+		fmt.Fprintln(w, ":commit")
+		missing = missingLats(rows, func(lat string) bool { return patternLats[lat] })
+	}
+	if v != "" {
+		statement, err := inline(registerQuery, map[string]interface{}{"version": string(v), "source": d.Directory})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, statement)
+	}
+	if err := w.Flush(); err != nil {
+		return &dicterr.WriteError{Path: out.Path, Err: err}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d patterns in %d LATs to %s.\n", count, len(d.Lats), out.Path)
+	reportMissingLats(missing)
+	return nil
+}
+
+/**
+ * Writes the import script for the dictionary without connecting to a
+ * database.
+ */
+func dryRun(directory string, opts options) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	t, err := dictionaryTones(d, opts.tones)
+	if err != nil {
+		return err
+	}
+	if err := writeScript(d, t, opts.dryRun, opts.version); err != nil {
+		return err
+	}
+	if opts.stats {
+		fmt.Fprintln(os.Stderr, "Missing words:", d.DefaultWordsCount,
+			d.MissingWordsCount, len(d.Words))
+	}
+	return nil
+}
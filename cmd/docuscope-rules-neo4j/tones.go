@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+/**
+ * Returns the query that merges a LAT's place in the tones hierarchy.  The
+ * :Lat node must already exist, so nothing links to a LAT without patterns.
+ *
+ * @param prefix: "$" for parameters or "row." for an UNWIND of $rows.
+ */
+func toneQuery(prefix string, v scope) string {
+	with := "d"
+	if prefix != "$" {
+		with = "d, row"
+	}
+	return fmt.Sprintf("MERGE (c:Cluster {name: %scluster%s}) MERGE (c)-[:HAS_DIMENSION]->(d:Dimension {name: %sdimension%s}) "+
+		"WITH %s MATCH (l:Lat {lat: %slat%s}) MERGE (d)-[:HAS_LAT]->(l);",
+		prefix, v.key(prefix), prefix, v.key(prefix), with, prefix, v.key(prefix))
+}
+
+/**
+ * Returns the query that lists the $lats that do not have a :Lat node.
+ */
+func missingLatsQuery(v scope) string {
+	return fmt.Sprintf("UNWIND $lats AS lat OPTIONAL MATCH (l:Lat {lat: lat%s}) WITH lat, l WHERE l IS NULL RETURN lat;", v.key("$"))
+}
+
+/**
+ * Returns the queries that delete the parts of the hierarchy that are no
+ * longer in _tones.txt.
+ */
+func pruneTonesQuery(v scope) []string {
+	return []string{
+		fmt.Sprintf("MATCH (c:Cluster%s)-[:HAS_DIMENSION]->(d:Dimension)-[r:HAS_LAT]->(l:Lat)%s DELETE r;",
+			v.node("$"), v.where("NOT [c.name, d.name, l.lat] IN $tones", "c")),
+		fmt.Sprintf("MATCH (c:Cluster%s)-[:HAS_DIMENSION]->(d:Dimension)%s DETACH DELETE d;",
+			v.node("$"), v.where("NOT [c.name, d.name] IN $dimensions", "c")),
+		fmt.Sprintf("MATCH (c:Cluster%s)%s DETACH DELETE c;", v.node("$"), v.where("NOT c.name IN $clusters", "c")),
+	}
+}
+
+/**
+ * Lists every cluster, dimension, and LAT of the tones as the parameters
+ * of toneQuery, sorted by cluster and dimension and then in file order.
+ */
+func toneRows(t tones.Tones, v scope) []map[string]interface{} {
+	clusters := make([]string, 0, len(t))
+	for cluster := range t {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	var rows []map[string]interface{}
+	for _, cluster := range clusters {
+		dimensions := t[cluster]
+		names := make([]string, 0, len(dimensions))
+		for dimension := range dimensions {
+			names = append(names, dimension)
+		}
+		sort.Strings(names)
+		for _, dimension := range names {
+			for _, lat := range dimensions[dimension] {
+				rows = append(rows, map[string]interface{}{"cluster": cluster, "dimension": dimension, "lat": lat, "version": string(v)})
+			}
+		}
+	}
+	return rows
+}
+
+/**
+ * Returns the tones of the dictionary if they are wanted, with --tones, or
+ * nil.  It is an error to want the tones of a dictionary without a
+ * _tones.txt.
+ */
+func dictionaryTones(d *dictionary.Dictionary, wanted bool) (tones.Tones, error) {
+	if !wanted {
+		return nil, nil
+	}
+	if d.Tones == nil {
+		return nil, fmt.Errorf("%s has no %s", d.Directory, dictionary.TonesFile)
+	}
+	return d.Tones, nil
+}
+
+/**
+ * Lists, sorted and without duplicates, the LATs of the tones rows that
+ * are not in the set of LATs with patterns.
+ */
+func missingLats(rows []map[string]interface{}, hasPatterns func(string) bool) []string {
+	missing := make(map[string]bool)
+	for _, row := range rows {
+		if lat := row["lat"].(string); !hasPatterns(lat) {
+			missing[lat] = true
+		}
+	}
+	return sortedKeys(missing)
+}
+
+/**
+ * Flags the tones LATs that have no pattern nodes in the graph.
+ */
+func reportMissingLats(missing []string) {
+	for _, lat := range missing {
+		fmt.Fprintf(os.Stderr, "Warning: LAT %q in %s has no patterns.\n", lat, dictionary.TonesFile)
+	}
+}
+
+/**
+ * Returns the parameters of the pruneTonesQuery for the tones rows.  The
+ * lists are empty rather than nil when there are no rows, as a null list
+ * would keep every node instead of pruning them all.
+ */
+func pruneTonesParams(rows []map[string]interface{}, v scope) map[string]interface{} {
+	entries := make([]interface{}, 0, len(rows))
+	dimensions := make([]interface{}, 0, len(rows))
+	clusters := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, []interface{}{row["cluster"], row["dimension"], row["lat"]})
+		dimensions = append(dimensions, []interface{}{row["cluster"], row["dimension"]})
+		clusters = append(clusters, row["cluster"])
+	}
+	return map[string]interface{}{"tones": entries, "dimensions": dimensions, "clusters": clusters, "version": string(v)}
+}
+
+/**
+ * Merges the tones hierarchy into the graph, linking each dimension to
+ * the existing :Lat nodes, and flags the LATs without :Lat nodes.
+ *
+ * @param prune: also delete the parts of the hierarchy that are not in
+ *   the dictionary.
+ */
+func writeTones(session neo4j.Session, t tones.Tones, batchSize int, v scope, prune bool) error {
+	rows := toneRows(t, v)
+	if err := writeRows(session, "UNWIND $rows AS row "+toneQuery("row.", v), rows, batchSize, "tones"); err != nil {
+		return err
+	}
+	if prune {
+		params := pruneTonesParams(rows, v)
+		_, txerr := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+			for _, query := range pruneTonesQuery(v) {
+				if _, err := tx.Run(query, params); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		})
+		if txerr != nil {
+			fmt.Printf("Error on tones transaction: %v\n", txerr)
+			return txerr
+		}
+	}
+
+	lats := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		lats = append(lats, row["lat"])
+	}
+	missing, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(missingLatsQuery(v), map[string]interface{}{"lats": lats, "version": string(v)})
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]bool)
+		for result.Next() {
+			lat, _ := result.Record().Get("lat")
+			found[fmt.Sprint(lat)] = true
+		}
+		return sortedKeys(found), result.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("could not check the tones LATs: %w", err)
+	}
+	reportMissingLats(missing.([]string))
+	fmt.Printf("Merged %d tones LATs.\n", len(rows))
+	return nil
+}
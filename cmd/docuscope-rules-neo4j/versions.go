@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+/*
+scope is the dictionary version that every node of an import is tagged with
+in its version property, so that several versions can be loaded side by side
+in one database.  The empty scope leaves the nodes untagged, which is the
+graph of a single dictionary; since its merges would match the tagged nodes
+as well, unversioned imports refuse to run on a database holding versions.
+*/
+type scope string
+
+/**
+ * Returns the version entry to add to the property map of a node key, eg.
+ * ", version: $version", or nothing when unversioned.
+ *
+ * @param prefix: "$" for a parameter or an UNWIND variable such as "row.".
+ */
+func (v scope) key(prefix string) string {
+	if v == "" {
+		return ""
+	}
+	return ", version: " + prefix + "version"
+}
+
+/**
+ * Returns the property map, eg. " {version: $version}", for a node that has
+ * no other key, or nothing when unversioned.
+ */
+func (v scope) node(prefix string) string {
+	if v == "" {
+		return ""
+	}
+	return " {version: " + prefix + "version}"
+}
+
+/**
+ * Returns a WHERE clause of the condition, which may be "", and, when
+ * unversioned, the conditions that the nodes have no version so that a
+ * match without a version key does not reach into the tagged versions.
+ *
+ * @param variables: the nodes matched without a version key.
+ */
+func (v scope) where(condition string, variables ...string) string {
+	var conditions []string
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+	if v == "" {
+		for _, variable := range variables {
+			conditions = append(conditions, variable+".version IS NULL")
+		}
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// The labels of the nodes of a version other than the :Start nodes and the
+// :NEXT chains, in the order they are dropped.
+var versionedLabels = []string{"Lat", "Word", "WordClass", "Dimension", "Cluster", "Dictionary"}
+
+// registerQuery records a loaded version.
+const registerQuery = "MERGE (d:Dictionary {version: $version}) SET d.source = $source, d.updated = datetime();"
+
+// listVersionsQuery lists the loaded versions with their number of
+// :Start nodes.
+const listVersionsQuery = "MATCH (d:Dictionary) OPTIONAL MATCH (s:Start {version: d.version}) " +
+	"RETURN d.version AS version, d.source AS source, toString(d.updated) AS updated, count(s) AS starts ORDER BY version;"
+
+// countVersionsQuery counts the loaded versions.
+const countVersionsQuery = "MATCH (d:Dictionary) RETURN count(d) AS versions;"
+
+// startsMatch matches the :Start nodes of a version and every node of
+// their :NEXT chains as n.
+const startsMatch = "MATCH p = (:Start {version: $version})-[:NEXT*0..]->(n)"
+
+/**
+ * Returns the queries that count and then delete the nodes of a version
+ * with the label, or the :Start nodes and their :NEXT chains for "Start".
+ * The delete query commits every batchSize nodes, so it must be run in an
+ * implicit transaction.
+ */
+func dropQueries(label string, batchSize int) (count string, drop string) {
+	match := fmt.Sprintf("MATCH (n:%s {version: $version})", label)
+	order := ""
+	if label == "Start" {
+		// Sorting reads every node before any is deleted, and the deepest
+		// nodes are deleted first so that the chains stay connected.
+		match, order = startsMatch, " WITH n ORDER BY length(p) DESC"
+	}
+	return match + " RETURN count(n) AS nodes;",
+		fmt.Sprintf("%s%s CALL { WITH n DETACH DELETE n } IN TRANSACTIONS OF %d ROWS;", match, order, batchSize)
+}
+
+/**
+ * Records the version and the dictionary it was loaded from.
+ */
+func registerVersion(session neo4j.Session, v scope, source string) error {
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		_, err := tx.Run(registerQuery, map[string]interface{}{"version": string(v), "source": source})
+		return nil, err
+	})
+	return err
+}
+
+/**
+ * Returns an error if the database holds any version, as the merges of an
+ * unversioned import would attach its patterns to the nodes of the versions.
+ */
+func checkUnversioned(session neo4j.Session) error {
+	versions, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(countVersionsQuery, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+		versions, _ := record.Get("versions")
+		return versions, nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not count the loaded versions: %w", err)
+	}
+	if n, _ := versions.(int64); n > 0 {
+		return fmt.Errorf("the database holds %d dictionary versions, use --dictionary-version to import alongside them", n)
+	}
+	return nil
+}
+
+/**
+ * Prints the versions loaded into the database.
+ */
+func listVersions(session neo4j.Session) error {
+	_, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(listVersionsQuery, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTART NODES\tUPDATED\tSOURCE")
+		for result.Next() {
+			record := result.Record()
+			version, _ := record.Get("version")
+			starts, _ := record.Get("starts")
+			updated, _ := record.Get("updated")
+			source, _ := record.Get("source")
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", version, starts, updated, source)
+		}
+		if err := result.Err(); err != nil {
+			return nil, err
+		}
+		return nil, w.Flush()
+	})
+	return err
+}
+
+/**
+ * Deletes every node of a version, batchSize nodes per transaction so that
+ * large versions do not need one huge transaction.
+ */
+func dropVersion(session neo4j.Session, v scope, batchSize int) error {
+	if v == "" {
+		return fmt.Errorf("no dictionary version to drop")
+	}
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	params := map[string]interface{}{"version": string(v)}
+	total := int64(0)
+	for _, label := range append([]string{"Start"}, versionedLabels...) {
+		count, drop := dropQueries(label, batchSize)
+		nodes, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+			result, err := tx.Run(count, params)
+			if err != nil {
+				return nil, err
+			}
+			record, err := result.Single()
+			if err != nil {
+				return nil, err
+			}
+			nodes, _ := record.Get("nodes")
+			return nodes, nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not count the :%s nodes of version %q: %w", label, v, err)
+		}
+		n, _ := nodes.(int64)
+		if n == 0 {
+			continue
+		}
+		total += n
+		result, err := session.Run(drop, params)
+		if err == nil {
+			_, err = result.Consume()
+		}
+		if err != nil {
+			return fmt.Errorf("could not drop version %q: %w", v, err)
+		}
+		fmt.Printf("\r%d", total)
+	}
+	fmt.Printf("\rDropped version %q: %d nodes.\n", v, total)
+	return nil
+}
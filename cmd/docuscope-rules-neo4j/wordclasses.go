@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+/**
+ * Returns the query that merges a word's membership in a word class.
+ *
+ * @param prefix: "$" for parameters or "row." for an UNWIND of $rows.
+ */
+func wordClassQuery(prefix string, v scope) string {
+	return fmt.Sprintf("MERGE (w:Word {word: %sword%s}) MERGE (c:WordClass {name: %sclass%s}) MERGE (w)-[:IN_CLASS]->(c);",
+		prefix, v.key(prefix), prefix, v.key(prefix))
+}
+
+/**
+ * Returns the query that lists the word class memberships in the graph.
+ */
+func graphMembershipsQuery(v scope) string {
+	return fmt.Sprintf("MATCH (w:Word%s)-[:IN_CLASS]->(c:WordClass)%s RETURN w.word AS word, c.name AS class;",
+		v.node("$"), v.where("", "w"))
+}
+
+/**
+ * Returns the query that deletes the word class memberships of an UNWIND
+ * of $rows.
+ */
+func deleteMembershipsQuery(v scope) string {
+	return fmt.Sprintf("UNWIND $rows AS row MATCH (w:Word {word: row.word%s})-[r:IN_CLASS]->(c:WordClass {name: row.class%s})%s DELETE r;",
+		v.key("row."), v.key("row."), v.where("", "w", "c"))
+}
+
+/**
+ * Returns the queries that delete the :Word and :WordClass nodes that are
+ * left without memberships.
+ */
+func pruneWordClassesQuery(v scope) []string {
+	return []string{
+		fmt.Sprintf("MATCH (w:Word%s)%s DELETE w;", v.node("$"), v.where("NOT (w)-[:IN_CLASS]->()", "w")),
+		fmt.Sprintf("MATCH (c:WordClass%s)%s DELETE c;", v.node("$"), v.where("NOT ()-[:IN_CLASS]->(c)", "c")),
+	}
+}
+
+/**
+ * Lists the memberships in the graph that are not among the rows of
+ * classMemberships.
+ */
+func staleMemberships(session neo4j.Session, rows []map[string]interface{}, v scope) ([]map[string]interface{}, error) {
+	desired := make(map[[2]string]bool, len(rows))
+	for _, row := range rows {
+		desired[[2]string{row["word"].(string), row["class"].(string)}] = true
+	}
+	stale, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(graphMembershipsQuery(v), map[string]interface{}{"version": string(v)})
+		if err != nil {
+			return nil, err
+		}
+		var stale []map[string]interface{}
+		for result.Next() {
+			word, _ := result.Record().Get("word")
+			class, _ := result.Record().Get("class")
+			if !desired[[2]string{fmt.Sprint(word), fmt.Sprint(class)}] {
+				stale = append(stale, map[string]interface{}{"word": word, "class": class, "version": string(v)})
+			}
+		}
+		return stale, result.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stale.([]map[string]interface{}), nil
+}
+
+/**
+ * Lists the word class memberships from _wordclasses.txt as the
+ * parameters of wordClassQuery, sorted by word and then in file order of
+ * the classes.  The class names keep the leading "!" so that they match
+ * the !CLASS tokens on :NEXT relationships.
+ */
+func classMemberships(d *dictionary.Dictionary, v scope) []map[string]interface{} {
+	words := make([]string, 0, len(d.Words))
+	for word := range d.Words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	var rows []map[string]interface{}
+	for _, word := range words {
+		// The first entry is the word itself.
+		for _, class := range d.Words[word][1:] {
+			rows = append(rows, map[string]interface{}{"word": word, "class": class, "version": string(v)})
+		}
+	}
+	return rows
+}
+
+/**
+ * Merges the word classes into the graph in batches.
+ *
+ * @param prune: also delete the memberships that are not in the dictionary.
+ */
+func writeWordClasses(session neo4j.Session, d *dictionary.Dictionary, batchSize int, v scope, prune bool) error {
+	rows := classMemberships(d, v)
+	if err := writeRows(session, "UNWIND $rows AS row "+wordClassQuery("row.", v), rows, batchSize, "word class"); err != nil {
+		return err
+	}
+	if prune {
+		stale, err := staleMemberships(session, rows, v)
+		if err != nil {
+			fmt.Printf("Error reading the word classes: %v\n", err)
+			return err
+		}
+		if err := writeRows(session, deleteMembershipsQuery(v), stale, batchSize, "word class"); err != nil {
+			return err
+		}
+		_, txerr := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+			for _, query := range pruneWordClassesQuery(v) {
+				if _, err := tx.Run(query, map[string]interface{}{"version": string(v)}); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		})
+		if txerr != nil {
+			fmt.Printf("Error on word class transaction: %v\n", txerr)
+			return txerr
+		}
+		fmt.Printf("Deleted %d word class memberships.\n", len(stale))
+	}
+	fmt.Printf("Merged %d word class memberships.\n", len(rows))
+	return nil
+}
@@ -4,6 +4,17 @@ Generate a single JSON representation of all of the LAT files in a directory.
 Usage:
 > docuscope_rules Dictionaries/default > rules.json
 > docuscope_rules Dictionaries/default | gzip > rules.json.gz
+> docuscope_rules -o rules.json.gz Dictionaries/default
+> docuscope_rules default-20210924.zip > rules.json
+> docuscope_rules --stream --temp-dir /scratch Dictionaries/default > rules.json
+
+With --stream the rules are written incrementally from sorted temporary files
+(see rules.Stream) so that memory use does not grow with the number of
+patterns.  The output is identical to the default in-memory mode.
+
+With --output the file is compressed with gzip (.gz) or zstd (.zst) based on
+its extension, or as given by --compress, and only replaces any existing
+file once it is complete.
 
 JSON schema: See api/docuscope_rules_schema.json
 
@@ -24,141 +35,135 @@ Example:
 	    "!BANG": ["bang"]
 	  }
 	}
+
+With --canonical every array is sorted (see rules.DocuScopeDictionary.Sort)
+so that the output is byte-stable and can be kept in version control.
+
+With --short-rules keep-all, shortRules values are lists: "word": ["Cat"]
 */
 package main
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"regexp"
 	"runtime"
 	"runtime/pprof"
-	"strings"
 
 	"github.com/urfave/cli/v2"
 
-	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/fix"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/output"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
 	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
-	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/wordclasses"
 )
 
-type RulesMap map[string]map[string]map[string][][]string
-
-/*
-DocuScopeDictionary contains the patterns and words in the dictionary used by
-DocuScope to parse a document.
-The rules are organized such that it is a partial reverse lookup with the
-initial bigram serving as the indicies to an array of arrays where the first
-element is the id of the LAT and the second is an array with the full pattern.
-The ShortRules are a mapping of unigram to LAT id.
-Words is a mapping of !CLASS or words to an array of words or classes.
-*/
-type DocuScopeDictionary struct {
-	Rules      RulesMap            `json:"rules"`
-	ShortRules map[string]string   `json:"shortRules"`
-	Words      map[string][]string `json:"words"`
+// Options for generating the rules.
+type options struct {
+	stats     bool
+	policy    rules.Policy
+	conflicts string // path for the conflicts report
+	stream    bool
+	runSize   int
+	tempDir   string
+	output    string // path of the rules file, stdout if empty
+	compress  output.Compression
+	canonical bool
 }
 
-/**
- * Add a pattern to the rules map.
- */
-func add(m RulesMap, lat string, rule []string) {
-	mm, ok := m[rule[0]]
-	if !ok {
-		mm = make(map[string]map[string][][]string)
-		m[rule[0]] = mm
-	}
-	mmm, ok := mm[rule[1]]
-	if !ok {
-		mmm = make(map[string][][]string)
-		mm[rule[1]] = mmm
-	}
-	mmm[lat] = append(mmm[lat], rule[2:])
-}
+func genDictionaryRules(directory string, opts options) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
 
-func genDictionaryRules(directory string, flagStats bool) error {
-	rules := make(RulesMap)
-	shortRules := make(map[string]string)
-	words := make(map[string][]string)
-	missingWordsCount := 0
-	defaultWordsCount := 0
-	patternRe := regexp.MustCompile(`[!?\w'-]+|[!"#$%&'()*+,-./:;<=>?@[\]^_\` + "`" + `{|}~]`)
-
-	wordclasses.ReadWords(words, filepath.Join(directory, "_wordclasses.txt"))
-	defaultWordsCount = len(words)
-	err := filepath.Walk(directory, func(path string,
-		info os.FileInfo, err error) error {
-		if err != nil {
-			fmt.Printf("Error: unable to access %q: %v\n",
-				path, err)
-			panic(err)
-		}
-		base := filepath.Base(path)
-		if !info.IsDir() && filepath.Ext(path) == ".txt" &&
-			!strings.HasPrefix(base, "_") {
-			lat := strings.TrimSuffix(base, ".txt")
-			content, err := os.Open(filepath.Clean(path))
-			if err != nil {
-				panic(err)
-			}
+	out, err := output.Create(opts.output, opts.compress)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
 
-			scanner := bufio.NewScanner(content)
-			for scanner.Scan() {
-				pattern := fix.Case(patternRe.FindAllString(scanner.Text(), -1))
-				switch len(pattern) {
-				case 0:
-					//noop
-				case 1:
-					shortRules[pattern[0]] = lat
-				default:
-					add(rules, lat, pattern)
-				}
-				for _, w := range pattern {
-					if wds, ok := words[w]; !ok {
-						words[w] = append(wds, w)
-						missingWordsCount++
-					}
-				}
-			}
-			if err := content.Close(); err != nil {
-				log.Fatal("Could not close content file: ", err)
+	var shortRules *rules.ShortRules
+	var b []byte
+	if opts.stream {
+		shortRules, err = rules.Stream(out, out.Path, d, opts.policy, opts.runSize, opts.tempDir, opts.canonical)
+	} else {
+		var dict *rules.DocuScopeDictionary
+		if dict, err = rules.Build(d, opts.policy); dict != nil {
+			shortRules = dict.ShortRules
+		}
+		if err == nil {
+			if opts.canonical {
+				dict.Sort()
 			}
+			b, err = json.Marshal(dict)
+		}
+	}
+	var conflictErr *rules.ConflictError
+	if err != nil && !errors.As(err, &conflictErr) {
+		return err
+	}
 
+	conflicts := shortRules.Conflicts()
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d single word patterns are used by multiple LATs (policy %s)\n",
+			len(conflicts), opts.policy)
+	}
+	if opts.conflicts != "" {
+		if err := writeConflicts(opts.conflicts, conflicts); err != nil {
+			return err
 		}
-		return nil
-	})
-	if err != nil {
-		panic(err)
+	}
+	if conflictErr != nil {
+		return conflictErr
 	}
 
-	if flagStats {
-		fmt.Fprintln(os.Stderr, "Missing words:", defaultWordsCount,
-			missingWordsCount, len(words))
+	if opts.stats {
+		fmt.Fprintln(os.Stderr, "Missing words:", d.DefaultWordsCount,
+			d.MissingWordsCount, len(d.Words))
 	}
 
-	b, err := json.Marshal(DocuScopeDictionary{rules, shortRules, words})
+	if b != nil {
+		if _, err := out.Write(b); err != nil {
+			return &dicterr.WriteError{Path: out.Path, Err: err}
+		}
+	}
+	return out.Close()
+}
+
+/**
+ * Writes the shortRules conflict report as a JSON array of
+ * {"word": <word>, "lats": [<lat>+]} objects sorted by word.
+ */
+func writeConflicts(path string, conflicts []rules.Conflict) error {
+	if conflicts == nil {
+		conflicts = []rules.Conflict{}
+	}
+	b, err := json.MarshalIndent(conflicts, "", "  ")
 	if err != nil {
-		panic(err)
+		return err
 	}
-	if _, err := os.Stdout.Write(b); err != nil {
-		panic(err)
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return &dicterr.WriteError{Path: path, Err: err}
 	}
 	return nil
 }
 
 func main() {
-	var flagStats bool
+	var opts options
+	var policy string
+	var compress string
 	var cpuprofile string
 	var memprofile string
 
 	app := &cli.App{
 		Name:      "DocuScope Rule File Generator",
 		Usage:     "Generates the JSON rules file from a directory containing LAT files and a _wordclasses.txt file.",
-		UsageText: "docuscope-rules Dictionaries/default | gzip > default.json.gz",
+		UsageText: "docuscope-rules -o default.json.gz Dictionaries/default",
 		Version:   "v1.0.4",
 		Authors: []*cli.Author{
 			&cli.Author{
@@ -170,7 +175,54 @@ func main() {
 			&cli.BoolFlag{
 				Name:        "stats",
 				Usage:       "Output statistics",
-				Destination: &flagStats,
+				Destination: &opts.stats,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Value:       "",
+				Usage:       "Write the rules to `file` instead of stdout, compressed according to its .gz or .zst extension",
+				Destination: &opts.output,
+			},
+			&cli.StringFlag{
+				Name:        "compress",
+				Value:       string(output.Auto),
+				Usage:       "Output `compression`: auto, none, gzip, or zstd",
+				Destination: &compress,
+			},
+			&cli.StringFlag{
+				Name:        "short-rules",
+				Value:       string(rules.LastWins),
+				Usage:       "`policy` for single word patterns used by multiple LATs: error, first-wins, last-wins, or keep-all",
+				Destination: &policy,
+			},
+			&cli.StringFlag{
+				Name:        "conflicts",
+				Value:       "",
+				Usage:       "Write a JSON report of single word patterns used by multiple LATs to `file`",
+				Destination: &opts.conflicts,
+			},
+			&cli.BoolFlag{
+				Name:        "canonical",
+				Usage:       "Sort all arrays so the output does not depend on file or line order",
+				Destination: &opts.canonical,
+			},
+			&cli.BoolFlag{
+				Name:        "stream",
+				Usage:       "Write the rules incrementally using sorted temporary files to bound memory use",
+				Destination: &opts.stream,
+			},
+			&cli.IntFlag{
+				Name:        "run-size",
+				Value:       rules.DefaultRunSize,
+				Usage:       "Number of patterns sorted in memory at a time with --stream",
+				Destination: &opts.runSize,
+			},
+			&cli.StringFlag{
+				Name:        "temp-dir",
+				Value:       "",
+				Usage:       "`directory` for the temporary files used by --stream, defaults to the system temporary directory",
+				Destination: &opts.tempDir,
 			},
 			&cli.StringFlag{
 				Name:        "cpuprofile",
@@ -186,7 +238,15 @@ func main() {
 			},
 		},
 		Action: func(c *cli.Context) error {
-			return genDictionaryRules(c.Args().First(), flagStats)
+			p, err := rules.ParsePolicy(policy)
+			if err != nil {
+				return err
+			}
+			opts.policy = p
+			if opts.compress, err = output.ParseCompression(compress); err != nil {
+				return err
+			}
+			return genDictionaryRules(c.Args().First(), opts)
 		},
 	}
 
@@ -201,7 +261,7 @@ func main() {
 		}
 		//defer pprof.StopCPUProfile()
 		if rerr := app.Run(os.Args); rerr != nil {
-			log.Fatal(rerr)
+			dicterr.Exit(rerr)
 		}
 
 		pprof.StopCPUProfile()
@@ -211,7 +271,7 @@ func main() {
 	} else {
 		err := app.Run(os.Args)
 		if err != nil {
-			log.Fatal(err)
+			dicterr.Exit(err)
 		}
 	}
 
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+const testDictionary = "../../testdata/dictionary"
+
+func TestGenDictionaryRules(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   options
+	}{
+		{"rules.golden", options{policy: rules.LastWins}},
+		{"rules.golden", options{policy: rules.LastWins, stream: true, runSize: 2}},
+		{"rules-first-wins.golden", options{policy: rules.FirstWins}},
+		{"rules-keep-all.golden", options{policy: rules.KeepAll}},
+		{"rules-keep-all.golden", options{policy: rules.KeepAll, stream: true, runSize: 3}},
+		{"rules-canonical.golden", options{policy: rules.KeepAll, canonical: true}},
+		{"rules-canonical.golden", options{policy: rules.KeepAll, canonical: true, stream: true, runSize: 2}},
+	}
+	for _, test := range tests {
+		actual, err := golden.Stdout(t, func() error {
+			return genDictionaryRules(testDictionary, test.opts)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, filepath.Join("testdata", test.golden), actual)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	conflicts := filepath.Join(t.TempDir(), "conflicts.json")
+	output, err := golden.Stdout(t, func() error {
+		return genDictionaryRules(testDictionary, options{policy: rules.Error, conflicts: conflicts})
+	})
+	if _, ok := err.(*rules.ConflictError); !ok {
+		t.Errorf("Expected a ConflictError but instead got %v!", err)
+	}
+	if len(output) > 0 {
+		t.Errorf("Expected no output with the error policy but instead got %s!", output)
+	}
+	actual, err := ioutil.ReadFile(conflicts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "conflicts.golden"), actual)
+}
@@ -0,0 +1,195 @@
+/*
+Summarize the LATs tagged in plain text files by tones cluster and dimension.
+
+Usage:
+> docuscope-summary --dictionary Dictionaries/default document.txt
+> docuscope-summary -d default.json.gz --tones _tones.txt --format csv *.txt
+
+Text is tagged the same way as docuscope-tag and the LAT hit counts are
+aggregated using the hierarchy in _tones.txt into dimension and cluster
+totals, both raw and per 1000 tokens.
+
+Output formats:
+  - json: an array with a summary for each file (see tones.Summary).
+  - csv: file,tokens,cluster,dimension,count,per1000 with one row per
+    dimension and a row per cluster with an empty dimension.
+*/
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tagger"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
+)
+
+func main() {
+	var dictionaryPath string
+	var tonesPath string
+	var format string
+	var policy string
+
+	app := &cli.App{
+		Name:      "DocuScope Tones Summary",
+		Usage:     "Tags plain text files, or standard input, and totals the LATs by tones cluster and dimension.",
+		UsageText: "docuscope-summary --dictionary Dictionaries/default [--tones _tones.txt] [--format json|csv] [file...]",
+		Version:   "v1.0.0",
+		Authors: []*cli.Author{
+			&cli.Author{
+				Name:  "Michael Ringenberg",
+				Email: unobfuscate.Unobfuscate("ringenbergATcmuDOTedu"),
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "dictionary",
+				Aliases:     []string{"d"},
+				Usage:       "Dictionary directory, .zip, .tar.gz, or .tgz archive, or docuscope-rules JSON `path`",
+				Required:    true,
+				Destination: &dictionaryPath,
+			},
+			&cli.StringFlag{
+				Name:        "tones",
+				Usage:       "Path to the _tones.txt `file`, defaults to the one in the dictionary directory or archive",
+				Destination: &tonesPath,
+			},
+			&cli.StringFlag{
+				Name:        "format",
+				Value:       "json",
+				Usage:       "Output `format`: json or csv",
+				Destination: &format,
+			},
+			&cli.StringFlag{
+				Name:        "short-rules",
+				Value:       string(rules.LastWins),
+				Usage:       "`policy` for single word patterns used by multiple LATs when tagging with a dictionary directory",
+				Destination: &policy,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			p, err := rules.ParsePolicy(policy)
+			if err != nil {
+				return err
+			}
+			return summarize(dictionaryPath, tonesPath, p, format, c.Args().Slice())
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		dicterr.Exit(err)
+	}
+}
+
+// FileSummary is the tones summary of a single file.
+type FileSummary struct {
+	File string `json:"file"`
+	tones.Summary
+}
+
+/**
+ * Returns a Tagger for the dictionary directory, archive, or rules file,
+ * and the tones of the _tones.txt file or, if none is given, of the
+ * dictionary.  A dictionary is loaded only once for both.
+ */
+func openDictionary(path string, tonesPath string, policy rules.Policy) (*tagger.Tagger, tones.Tones, error) {
+	var t tones.Tones
+	if tonesPath != "" {
+		var err error
+		if t, err = tones.ReadFile(tonesPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() && !dictionary.IsArchive(path) {
+		if t == nil {
+			return nil, nil, fmt.Errorf("--tones is required when the dictionary is a rules file")
+		}
+		tag, err := tagger.Open(path, policy)
+		return tag, t, err
+	}
+	d, err := dictionary.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer d.Close()
+	if t == nil {
+		if d.Tones == nil {
+			return nil, nil, fmt.Errorf("%s has no %s, use --tones", d.Directory, dictionary.TonesFile)
+		}
+		t = d.Tones
+	}
+	tag, err := tagger.FromDictionary(d, policy)
+	return tag, t, err
+}
+
+func summarize(dictionaryPath string, tonesPath string, policy rules.Policy, format string, files []string) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	tag, t, err := openDictionary(dictionaryPath, tonesPath, policy)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	summaries := make([]FileSummary, 0, len(files))
+	for _, file := range files {
+		text, err := tagger.ReadText(file)
+		if err != nil {
+			return err
+		}
+		tokens := tagger.Tokenize(text)
+		counts := tagger.Count(tag.Tag(tokens))
+		summaries = append(summaries, FileSummary{file, tones.Summarize(t, counts, len(tokens))})
+	}
+
+	if format == "csv" {
+		err = writeCSV(summaries)
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(summaries)
+	}
+	if err != nil {
+		return &dicterr.WriteError{Path: "stdout", Err: err}
+	}
+	return nil
+}
+
+func writeCSV(summaries []FileSummary) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write([]string{"file", "tokens", "cluster", "dimension", "count", "per1000"}); err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		tokens := strconv.Itoa(s.Tokens)
+		for _, c := range s.Clusters {
+			if err := w.Write([]string{s.File, tokens, c.Name, "",
+				strconv.Itoa(c.Count), formatFloat(c.PerThousand)}); err != nil {
+				return err
+			}
+			for _, d := range c.Dimensions {
+				if err := w.Write([]string{s.File, tokens, c.Name, d.Name,
+					strconv.Itoa(d.Count), formatFloat(d.PerThousand)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+func TestSummarize(t *testing.T) {
+	for _, format := range []string{"json", "csv"} {
+		actual, err := golden.Stdout(t, func() error {
+			return summarize("../../testdata/dictionary", "", rules.LastWins, format,
+				[]string{"../../testdata/text.txt"})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, filepath.Join("testdata", "summary-"+format+".golden"), actual)
+	}
+}
+
+func TestSummarizeArchive(t *testing.T) {
+	archive := golden.Zip(t, "../../testdata/dictionary", "")
+	actual, err := golden.Stdout(t, func() error {
+		return summarize(archive, "", rules.LastWins, "json", []string{"../../testdata/text.txt"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "summary-json.golden"), actual)
+}
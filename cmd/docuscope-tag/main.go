@@ -0,0 +1,253 @@
+/*
+Tag plain text files with the LATs of a DocuScope dictionary.
+
+Usage:
+> docuscope-tag --dictionary Dictionaries/default document.txt
+> echo "I think so." | docuscope-tag --dictionary default.json.gz --format csv
+
+The dictionary can be a dictionary directory or the (gzip or zstd compressed)
+JSON generated by docuscope-rules.  Tagging uses the reference implementation in
+internal/pkg/tagger which is longest match first with !CLASS expansion.
+
+Output formats:
+  - jsonl: one JSON object per token:
+    {"file": <string>, "token": <index>, "offset": <byte offset>,
+    "text": <string>, "lat": <LAT id>, "span": <1-based LAT span index>}
+    where lat and span are omitted for untagged tokens.
+  - csv: the same fields as jsonl with a header row.
+  - html: the text with each LAT span wrapped in
+    <span class="lat" data-lat="<LAT id>">.
+*/
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tagger"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
+)
+
+func main() {
+	var dictionaryPath string
+	var format string
+	var policy string
+
+	app := &cli.App{
+		Name:      "DocuScope Tagger",
+		Usage:     "Tags plain text files, or standard input, with the LATs of a DocuScope dictionary.",
+		UsageText: "docuscope-tag --dictionary Dictionaries/default [--format jsonl|csv|html] [file...]",
+		Version:   "v1.0.0",
+		Authors: []*cli.Author{
+			&cli.Author{
+				Name:  "Michael Ringenberg",
+				Email: unobfuscate.Unobfuscate("ringenbergATcmuDOTedu"),
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "dictionary",
+				Aliases:     []string{"d"},
+				Usage:       "Dictionary directory or docuscope-rules JSON `path`",
+				Required:    true,
+				Destination: &dictionaryPath,
+			},
+			&cli.StringFlag{
+				Name:        "format",
+				Value:       "jsonl",
+				Usage:       "Output `format`: jsonl, csv, or html",
+				Destination: &format,
+			},
+			&cli.StringFlag{
+				Name:        "short-rules",
+				Value:       string(rules.LastWins),
+				Usage:       "`policy` for single word patterns used by multiple LATs when tagging with a dictionary directory",
+				Destination: &policy,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			p, err := rules.ParsePolicy(policy)
+			if err != nil {
+				return err
+			}
+			return tagFiles(dictionaryPath, p, format, c.Args().Slice())
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		dicterr.Exit(err)
+	}
+}
+
+func tagFiles(dictionaryPath string, policy rules.Policy, format string, files []string) error {
+	var out writer
+	switch format {
+	case "jsonl":
+		out = &jsonlWriter{json.NewEncoder(os.Stdout)}
+	case "csv":
+		out = &csvWriter{w: csv.NewWriter(os.Stdout)}
+	case "html":
+		out = &htmlWriter{os.Stdout}
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	t, err := tagger.Open(dictionaryPath, policy)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if err := out.Begin(); err != nil {
+		return &dicterr.WriteError{Path: "stdout", Err: err}
+	}
+	for _, file := range files {
+		text, err := tagger.ReadText(file)
+		if err != nil {
+			return err
+		}
+		tokens := tagger.Tokenize(text)
+		if err := out.Document(file, text, tokens, t.Tag(tokens)); err != nil {
+			return &dicterr.WriteError{Path: "stdout", Err: err}
+		}
+	}
+	if err := out.End(); err != nil {
+		return &dicterr.WriteError{Path: "stdout", Err: err}
+	}
+	return nil
+}
+
+// Annotation is the LAT tag of a single token.
+type Annotation struct {
+	File   string `json:"file"`
+	Token  int    `json:"token"`
+	Offset int    `json:"offset"`
+	Text   string `json:"text"`
+	Lat    string `json:"lat,omitempty"`
+	Span   int    `json:"span,omitempty"`
+}
+
+/**
+ * Lists the annotation for every token where Span is the 1-based index of
+ * the LAT span containing the token or 0 if it is untagged.
+ */
+func annotate(file string, tokens []tagger.Token, spans []tagger.Span) []Annotation {
+	annotations := make([]Annotation, len(tokens))
+	for i, token := range tokens {
+		annotations[i] = Annotation{File: file, Token: i, Offset: token.Offset, Text: token.Text}
+	}
+	for s, span := range spans {
+		for i := span.Start; i < span.End; i++ {
+			annotations[i].Lat = span.Lat
+			annotations[i].Span = s + 1
+		}
+	}
+	return annotations
+}
+
+// writer outputs the tagged documents in a given format.
+type writer interface {
+	Begin() error
+	Document(file string, text string, tokens []tagger.Token, spans []tagger.Span) error
+	End() error
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Begin() error { return nil }
+
+func (w *jsonlWriter) Document(file string, text string, tokens []tagger.Token, spans []tagger.Span) error {
+	for _, a := range annotate(file, tokens, spans) {
+		if err := w.encoder.Encode(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *jsonlWriter) End() error { return nil }
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Begin() error {
+	return w.w.Write([]string{"file", "token", "offset", "text", "lat", "span"})
+}
+
+func (w *csvWriter) Document(file string, text string, tokens []tagger.Token, spans []tagger.Span) error {
+	for _, a := range annotate(file, tokens, spans) {
+		span := ""
+		if a.Span > 0 {
+			span = strconv.Itoa(a.Span)
+		}
+		if err := w.w.Write([]string{a.File, strconv.Itoa(a.Token),
+			strconv.Itoa(a.Offset), a.Text, a.Lat, span}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) End() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type htmlWriter struct {
+	w io.Writer
+}
+
+func (w *htmlWriter) Begin() error {
+	_, err := io.WriteString(w.w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DocuScope Tags</title>
+<style>
+.document { white-space: pre-wrap; }
+.lat { border-bottom: 2px solid #4a90d9; }
+</style>
+</head>
+<body>
+`)
+	return err
+}
+
+func (w *htmlWriter) Document(file string, text string, tokens []tagger.Token, spans []tagger.Span) error {
+	if _, err := fmt.Fprintf(w.w, "<h2>%s</h2>\n<div class=\"document\">", html.EscapeString(file)); err != nil {
+		return err
+	}
+	pos := 0
+	for _, span := range spans {
+		start := tokens[span.Start].Offset
+		last := tokens[span.End-1]
+		end := last.Offset + len(last.Text)
+		lat := html.EscapeString(span.Lat)
+		if _, err := fmt.Fprintf(w.w, `%s<span class="lat" data-lat="%s" title="%s">%s</span>`,
+			html.EscapeString(text[pos:start]), lat, lat,
+			html.EscapeString(text[start:end])); err != nil {
+			return err
+		}
+		pos = end
+	}
+	_, err := fmt.Fprintf(w.w, "%s</div>\n", html.EscapeString(text[pos:]))
+	return err
+}
+
+func (w *htmlWriter) End() error {
+	_, err := io.WriteString(w.w, "</body>\n</html>\n")
+	return err
+}
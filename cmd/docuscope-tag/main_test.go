@@ -0,0 +1,22 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+func TestTagFiles(t *testing.T) {
+	for _, format := range []string{"jsonl", "csv", "html"} {
+		actual, err := golden.Stdout(t, func() error {
+			return tagFiles("../../testdata/dictionary", rules.LastWins, format,
+				[]string{"../../testdata/text.txt"})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, filepath.Join("testdata", "tag-"+format+".golden"), actual)
+	}
+}
@@ -1,55 +1,48 @@
 /*
 Tool for generating DocuScope json tones files.
 
-Usage: docuscope_tones < _tones.txt > tones.json
+Usage:
+> docuscope_tones < _tones.txt > tones.json
+> docuscope_tones --dictionary Dictionaries/default > tones.json
+
+With --dictionary, the _tones.txt file of the dictionary is converted and
+checked against the dictionary's LAT files.  Problems are reported on stderr
+and the exit status is 65 if any are errors.
+
+With --canonical the LATs, or listings with --extended, of each dimension
+are sorted by LAT id (and then kind) so that the output is byte-stable.
 
 JSON Schema: see api/docuscope_tones_schema.json
 */
 package main
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
-	"strings"
 
 	"github.com/urfave/cli/v2"
 
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
 	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
 )
 
-func add(m map[string]map[string][]string, cluster string, dimension string, lats []string) {
-	mm, ok := m[cluster]
-	if !ok {
-		mm = make(map[string][]string)
-		m[cluster] = mm
-	}
-	// pushnew lats onto existing (no duplicates).
-	// This handles the problem where a given tone is repeated.
-	// This should probably be broader to check for no lat duplicates
-	// as that will cause errors in docuscope-tag as it will complain
-	// that indicies should be unique.
-	for _, lat := range lats {
-		to_add := true
-		for _, ele := range mm[dimension] {
-			if ele == lat {
-				to_add = false
-				break
-			}
-		}
-		if to_add {
-			mm[dimension] = append(mm[dimension], lat)
-		}
-	}
+// Options for the tones output.
+type options struct {
+	extended  bool
+	canonical bool
 }
 
 func main() {
+	var directory string
+	var opts options
+
 	app := &cli.App{
 		Name:      "DocuScope Tones Converter",
 		Usage:     "Convert a DocuScope _tone.txt file to json.",
-		UsageText: "docuscope-tones < _tones.txt > tones.json",
+		UsageText: "docuscope-tones < _tones.txt > tones.json\n   docuscope-tones --dictionary Dictionaries/default > tones.json",
 		Version:   "v1.0.0",
 		Authors: []*cli.Author{
 			&cli.Author{
@@ -57,45 +50,104 @@ func main() {
 				Email: unobfuscate.Unobfuscate("ringenbergATcmuDOTedu"),
 			},
 		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "dictionary",
+				Usage:       "Convert the _tones.txt in the dictionary `directory` and check it against the LAT files",
+				Destination: &directory,
+			},
+			&cli.BoolFlag{
+				Name:        "extended",
+				Usage:       "Output {\"lat\", \"kind\"} objects that keep the LAT, LAT*, or CLASS kind of each listing",
+				Destination: &opts.extended,
+			},
+			&cli.BoolFlag{
+				Name:        "canonical",
+				Usage:       "Sort the LATs of each dimension so the output does not depend on line order",
+				Destination: &opts.canonical,
+			},
+		},
 		Action: func(c *cli.Context) error {
-			return tonesToJson()
+			if directory != "" {
+				return checkedTonesToJson(directory, opts)
+			}
+			return tonesToJson(opts)
 		},
 	}
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		dicterr.Exit(err)
 	}
 }
 
-func tonesToJson() error {
-	var cluster string
-	var dimension string
-	tones := make(map[string]map[string][]string)
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := strings.Fields(scanner.Text())
-		if len(line) > 1 {
-			switch line[0] {
-			case "CLUSTER:":
-				cluster = line[1]
-			case "DIMENSION:":
-				dimension = line[1]
-			case "LAT:", "LAT*:", "CLASS:":
-				//add to tones
-				add(tones, cluster, dimension, line[1:])
-			default:
-				//noop
-			}
-		}
+func tonesToJson(opts options) error {
+	f, err := tones.Parse(os.Stdin, "stdin")
+	if err != nil {
+		return err
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading standard input:", err)
+	return writeTones(f, opts)
+}
+
+/**
+ * Writes the tones hierarchy, with listing kinds if extended, to stdout.
+ */
+func writeTones(f *tones.File, opts options) error {
+	var b []byte
+	var err error
+	if opts.extended {
+		t := f.ExtendedTones()
+		if opts.canonical {
+			t.Sort()
+		}
+		b, err = json.Marshal(t)
+	} else {
+		t := f.Tones()
+		if opts.canonical {
+			t.Sort()
+		}
+		b, err = json.Marshal(t)
 	}
-	b, err := json.Marshal(tones)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error outputting:", err)
+		return err
 	}
 	if _, err := os.Stdout.Write(b); err != nil {
-		log.Fatal(err)
+		return &dicterr.WriteError{Path: "stdout", Err: err}
+	}
+	return nil
+}
+
+/**
+ * Converts the _tones.txt file of a dictionary after reporting any
+ * inconsistencies with the dictionary's LATs.
+ */
+func checkedTonesToJson(directory string, opts options) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	f, err := d.ParseTones()
+	if err != nil {
+		return err
+	}
+	lats := make([]string, len(d.Lats))
+	for i, lat := range d.Lats {
+		lats[i] = lat.Name
+	}
+	errorCount := 0
+	problems := f.Validate(lats)
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+		if p.Severity == "error" {
+			errorCount++
+		}
+	}
+
+	if err := writeTones(f, opts); err != nil {
+		return err
+	}
+	if errorCount > 0 {
+		return cli.Exit(fmt.Sprintf("%d errors, %d warnings", errorCount,
+			len(problems)-errorCount), dicterr.ExitBadInput)
 	}
 	return nil
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+func TestCheckedTonesToJson(t *testing.T) {
+	tests := map[string]options{
+		"tones.golden":           {},
+		"tones-extended.golden":  {extended: true},
+		"tones-canonical.golden": {extended: true, canonical: true},
+	}
+	for golden_, opts := range tests {
+		actual, err := golden.Stdout(t, func() error {
+			return checkedTonesToJson("../../testdata/dictionary", opts)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, filepath.Join("testdata", golden_), actual)
+	}
+}
+
+func TestCheckedTonesToJsonArchive(t *testing.T) {
+	archive := golden.Zip(t, "../../testdata/dictionary", "dictionary")
+	actual, err := golden.Stdout(t, func() error {
+		return checkedTonesToJson(archive, options{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "tones.golden"), actual)
+}
+
+func TestWriteTonesCanonical(t *testing.T) {
+	// The LATs of each dimension are out of order in the file.
+	f, err := tones.ParseFile(filepath.Join("testdata", "unsorted_tones.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]options{
+		"tones-unsorted.golden": {},
+		"tones-sorted.golden":   {canonical: true},
+	}
+	for golden_, opts := range tests {
+		actual, err := golden.Stdout(t, func() error {
+			return writeTones(f, opts)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, filepath.Join("testdata", golden_), actual)
+	}
+}
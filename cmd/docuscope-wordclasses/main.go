@@ -4,6 +4,11 @@ Generate a single JSON representation of all of the wordclasses.
 Usage:
 > docuscope_wordclasses Dictionaries/default > wordclasses.json
 > docuscope_wordclasses Dictionaries/default | gzip > wordclasses.json.gz
+> docuscope_wordclasses -o wordclasses.json.zst Dictionaries/default
+> docuscope_wordclasses default-20210924.zip > wordclasses.json
+
+With --canonical the classes of each word are sorted, leaving the word
+itself first, so the output is byte-stable.
 
 JSON schema: See api/docuscope_wordclasses_schema.json
 
@@ -16,94 +21,71 @@ Example:
 package main
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"regexp"
 	"runtime"
 	"runtime/pprof"
-	"strings"
 
 	"github.com/urfave/cli/v2"
 
-	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/fix"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/output"
 	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/unobfuscate"
 	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/wordclasses"
 )
 
 type WordsMap map[string][]string
 
-func genWordclasses(directory string, flagStats bool) error {
-	words := make(WordsMap)
-	missingWordsCount := 0
-	defaultWordsCount := 0
-	patternRe := regexp.MustCompile(`[!?\w'-]+|[!"#$%&'()*+,-./:;<=>?@[\]^_\` + "`" + `{|}~]`)
-
-	wordclasses.ReadWords(words, filepath.Join(directory, "_wordclasses.txt"))
-	defaultWordsCount = len(words)
-	err := filepath.Walk(directory, func(path string,
-		info os.FileInfo, err error) error {
-		if err != nil {
-			fmt.Printf("Error: unable to access %q: %v\n",
-				path, err)
-			panic(err)
-		}
-		base := filepath.Base(path)
-		if !info.IsDir() && filepath.Ext(path) == ".txt" &&
-			!strings.HasPrefix(base, "_") {
-			content, err := os.Open(filepath.Clean(path))
-			if err != nil {
-				panic(err)
-			}
-
-			scanner := bufio.NewScanner(content)
-			for scanner.Scan() {
-				pattern := fix.Case(patternRe.FindAllString(scanner.Text(), -1))
-				for _, w := range pattern {
-					if wds, ok := words[w]; !ok {
-						words[w] = append(wds, w)
-						missingWordsCount++
-					}
-				}
-			}
-			if err := content.Close(); err != nil {
-				log.Fatal("Could not close content file: ", err)
-			}
-
-		}
-		return nil
-	})
+func genWordclasses(directory string, flagStats bool, canonical bool, path string, compress output.Compression) error {
+	d, err := dictionary.Load(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// Walking the patterns adds any words missing from _wordclasses.txt.
+	err = d.Walk(func(dictionary.Pattern) error { return nil })
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	if flagStats {
-		fmt.Fprintln(os.Stderr, "Missing words:", defaultWordsCount,
-			missingWordsCount, len(words))
+		fmt.Fprintln(os.Stderr, "Missing words:", d.DefaultWordsCount,
+			d.MissingWordsCount, len(d.Words))
 	}
 
-	b, err := json.Marshal(words)
+	if canonical {
+		wordclasses.Sort(d.Words)
+	}
+	b, err := json.Marshal(WordsMap(d.Words))
+	if err != nil {
+		return err
+	}
+	out, err := output.Create(path, compress)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	if _, err := os.Stdout.Write(b); err != nil {
-		panic(err)
+	defer out.Abort()
+	if _, err := out.Write(b); err != nil {
+		return &dicterr.WriteError{Path: out.Path, Err: err}
 	}
-	return nil
+	return out.Close()
 }
 
 func main() {
 	var flagStats bool
+	var canonical bool
+	var outputPath string
+	var compress string
 	var cpuprofile string
 	var memprofile string
 
 	app := &cli.App{
 		Name:      "DocuScope Word Classes Generator",
 		Usage:     "Generates the JSON wordclasses file from a directory containing LAT files and a _wordclasses.txt file.",
-		UsageText: "docuscope-wordclasses Dictionaries/default | gzip > wordclasses.json.gz",
+		UsageText: "docuscope-wordclasses -o wordclasses.json.gz Dictionaries/default",
 		Version:   "v1.0.0",
 		Authors: []*cli.Author{
 			&cli.Author{
@@ -117,6 +99,24 @@ func main() {
 				Usage:       "Output statistics",
 				Destination: &flagStats,
 			},
+			&cli.BoolFlag{
+				Name:        "canonical",
+				Usage:       "Sort the classes of each word so the output does not depend on file order",
+				Destination: &canonical,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Value:       "",
+				Usage:       "Write the word classes to `file` instead of stdout, compressed according to its .gz or .zst extension",
+				Destination: &outputPath,
+			},
+			&cli.StringFlag{
+				Name:        "compress",
+				Value:       string(output.Auto),
+				Usage:       "Output `compression`: auto, none, gzip, or zstd",
+				Destination: &compress,
+			},
 			&cli.StringFlag{
 				Name:        "cpuprofile",
 				Value:       "",
@@ -131,7 +131,11 @@ func main() {
 			},
 		},
 		Action: func(c *cli.Context) error {
-			return genWordclasses(c.Args().First(), flagStats)
+			compression, err := output.ParseCompression(compress)
+			if err != nil {
+				return err
+			}
+			return genWordclasses(c.Args().First(), flagStats, canonical, outputPath, compression)
 		},
 	}
 
@@ -146,7 +150,7 @@ func main() {
 		}
 		//defer pprof.StopCPUProfile()
 		if rerr := app.Run(os.Args); rerr != nil {
-			log.Fatal(rerr)
+			dicterr.Exit(rerr)
 		}
 
 		pprof.StopCPUProfile()
@@ -156,7 +160,7 @@ func main() {
 	} else {
 		err := app.Run(os.Args)
 		if err != nil {
-			log.Fatal(err)
+			dicterr.Exit(err)
 		}
 	}
 
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/golden"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/output"
+)
+
+func TestGenWordclasses(t *testing.T) {
+	actual, err := golden.Stdout(t, func() error {
+		return genWordclasses("../../testdata/dictionary", false, false, "", output.Auto)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, filepath.Join("testdata", "wordclasses.golden"), actual)
+}
@@ -1,14 +1,13 @@
 module gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules
 
-go 1.13
+go 1.16
 
 require (
 	github.com/golobby/dotenv v1.3.1
-	github.com/kr/pretty v0.3.0 // indirect
+	github.com/klauspost/compress v1.13.6
+	github.com/klauspost/pgzip v1.2.5
 	github.com/neo4j/neo4j-go-driver/v5 v5.8.0
-	github.com/rogpeppe/go-internal v1.8.1 // indirect
 	github.com/stretchr/testify v1.8.0 // indirect
 	github.com/urfave/cli/v2 v2.11.1
 	golang.org/x/text v0.3.7
-	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
@@ -0,0 +1,148 @@
+/*
+Package dicterr defines the errors reported when reading DocuScope
+dictionary files and the command exit codes that correspond to them.
+*/
+package dicterr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Exit codes used by the commands, following sysexits.h.
+const (
+	ExitOK       = 0
+	ExitFailure  = 1  // Any error not otherwise classified.
+	ExitBadInput = 65 // EX_DATAERR: the dictionary or input is malformed.
+	ExitIOError  = 74 // EX_IOERR: a file could not be read or written.
+)
+
+// MissingWordClassesError reports that a dictionary has no _wordclasses.txt.
+type MissingWordClassesError struct {
+	Path string
+	Err  error
+}
+
+func (e *MissingWordClassesError) Error() string {
+	return fmt.Sprintf("%s: missing word classes file", e.Path)
+}
+
+func (e *MissingWordClassesError) Unwrap() error { return e.Err }
+
+/*
+ReadError reports a dictionary file, such as a LAT file, that could not be
+read.  Line is the line being read when the error occurred or 0 if the file
+could not be opened.
+*/
+type ReadError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *ReadError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *ReadError) Unwrap() error { return e.Err }
+
+// FormatError reports input, such as a rules JSON file, that is malformed.
+type FormatError struct {
+	Path string
+	Err  error
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FormatError) Unwrap() error { return e.Err }
+
+// WriteError reports output that could not be written.
+type WriteError struct {
+	Path string
+	Err  error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// LineTooLongError reports a line that overflows the scanner buffer.
+type LineTooLongError struct {
+	Path string
+	Line int
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("%s:%d: line too long", e.Path, e.Line)
+}
+
+func (e *LineTooLongError) Unwrap() error { return bufio.ErrTooLong }
+
+/*
+BadInput is implemented by errors, from other packages, that are caused by
+malformed input and should exit with ExitBadInput.
+*/
+type BadInput interface {
+	error
+	BadInput() bool
+}
+
+/**
+ * Converts the error from bufio.Scanner.Err into a typed error.
+ *
+ * @param path: the file being scanned.
+ * @param line: the number of the line being scanned when the error occurred.
+ * @param err: the scanner error, may be nil.
+ */
+func Scan(path string, line int, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bufio.ErrTooLong):
+		return &LineTooLongError{path, line}
+	default:
+		return &ReadError{path, line, err}
+	}
+}
+
+/**
+ * Returns the exit code a command should use when failing with err.
+ */
+func ExitCode(err error) int {
+	var missing *MissingWordClassesError
+	var tooLong *LineTooLongError
+	var format *FormatError
+	var read *ReadError
+	var write *WriteError
+	var badInput BadInput
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &missing), errors.As(err, &tooLong), errors.As(err, &format):
+		return ExitBadInput
+	case errors.As(err, &badInput) && badInput.BadInput():
+		return ExitBadInput
+	case errors.As(err, &read), errors.As(err, &write):
+		return ExitIOError
+	default:
+		return ExitFailure
+	}
+}
+
+/**
+ * Logs err and exits with the corresponding exit code.
+ */
+func Exit(err error) {
+	log.Print(err)
+	os.Exit(ExitCode(err))
+}
@@ -0,0 +1,295 @@
+package dictionary
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+/**
+ * Reports if the path names a dictionary archive, a .zip, .tar.gz, or .tgz
+ * file, by its extension.
+ */
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".zip") ||
+		strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+/**
+ * Opens a dictionary directory or archive as a file system.
+ * If an archive has a single top level directory, as release archives
+ * usually do, and not a _wordclasses.txt file, the file system is rooted
+ * at that directory.
+ * The file system of a .zip archive reads from the open archive and
+ * implements io.Closer; close it when done.
+ *
+ * @param path: a dictionary directory or .zip, .tar.gz, or .tgz archive.
+ * @return the file system and the name to use for it in messages.
+ */
+func Open(path string) (fs.FS, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, path, &dicterr.ReadError{Path: path, Err: err}
+	}
+	if info.IsDir() {
+		return os.DirFS(path), path, nil
+	}
+
+	var fsys fs.FS
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		fsys, err = openZip(path)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		fsys, err = openTarGz(path)
+	default:
+		return nil, path, &dicterr.FormatError{Path: path,
+			Err: errors.New("not a dictionary directory or .zip, .tar.gz, or .tgz archive")}
+	}
+	if err != nil {
+		return nil, path, err
+	}
+	root, name, err := archiveRoot(fsys, path)
+	closer, ok := fsys.(io.Closer)
+	switch {
+	case !ok:
+		return root, name, err
+	case err != nil:
+		closer.Close()
+		return nil, name, err
+	}
+	return closingFS{root, closer}, name, nil
+}
+
+// closingFS is the root of an archive that must be closed when done.
+type closingFS struct {
+	fs.FS
+	io.Closer
+}
+
+/**
+ * Descends into the single top level directory of an archive without a
+ * _wordclasses.txt file.
+ */
+func archiveRoot(fsys fs.FS, name string) (fs.FS, string, error) {
+	if _, err := fs.Stat(fsys, WordClassesFile); err == nil {
+		return fsys, name, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, name, &dicterr.ReadError{Path: name, Err: err}
+	}
+	var dirs []string
+	for _, entry := range entries {
+		// Skip metadata directories like those added by macOS.
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") &&
+			!strings.HasPrefix(entry.Name(), "__") {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	if len(dirs) != 1 {
+		return fsys, name, nil
+	}
+	sub, err := fs.Sub(fsys, dirs[0])
+	if err != nil {
+		return nil, name, &dicterr.ReadError{Path: name, Err: err}
+	}
+	return sub, filepath.Join(name, dirs[0]), nil
+}
+
+func openZip(path string) (fs.FS, error) {
+	r, err := zip.OpenReader(filepath.Clean(path))
+	if errors.Is(err, zip.ErrFormat) || errors.Is(err, zip.ErrAlgorithm) || errors.Is(err, zip.ErrChecksum) {
+		return nil, &dicterr.FormatError{Path: path, Err: err}
+	} else if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	return r, nil
+}
+
+/**
+ * Reads the regular files of a gzip compressed tar archive into memory.
+ */
+func openTarGz(archive string) (fs.FS, error) {
+	f, err := os.Open(filepath.Clean(archive))
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: archive, Err: err}
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, &dicterr.FormatError{Path: archive, Err: err}
+	}
+
+	fsys := newMemFS()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, &dicterr.FormatError{Path: archive, Err: err}
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			return nil, &dicterr.FormatError{Path: archive,
+				Err: fmt.Errorf("invalid file name %q", header.Name)}
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, &dicterr.FormatError{Path: archive, Err: err}
+		}
+		fsys.add(name, data, fs.FileMode(header.Mode).Perm(), header.ModTime)
+	}
+	return fsys, nil
+}
+
+/*
+memFS is a read-only in-memory file system holding the regular files of an
+archive and the directories they imply.
+*/
+type memFS struct {
+	files map[string]*memFile // by path, including "." for the root
+}
+
+/*
+memFile is a file or directory in a memFS.
+It is its own fs.FileInfo and fs.DirEntry.
+*/
+type memFile struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+	entries []fs.DirEntry // of a directory
+}
+
+func (f *memFile) Name() string               { return f.name }
+func (f *memFile) Size() int64                { return int64(len(f.data)) }
+func (f *memFile) Mode() fs.FileMode          { return f.mode }
+func (f *memFile) ModTime() time.Time         { return f.modTime }
+func (f *memFile) IsDir() bool                { return f.mode.IsDir() }
+func (f *memFile) Sys() interface{}           { return nil }
+func (f *memFile) Type() fs.FileMode          { return f.mode.Type() }
+func (f *memFile) Info() (fs.FileInfo, error) { return f, nil }
+
+// openFile is a memFile opened for reading.
+type openFile struct {
+	*bytes.Reader
+	file *memFile
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.file, nil }
+func (f *openFile) Close() error               { return nil }
+
+// openDir is a directory of a memFS opened for listing.
+type openDir struct {
+	file    *memFile
+	entries []fs.DirEntry // remaining to be listed
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.file, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.file.name, Err: errors.New("is a directory")}
+}
+
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		} else if n < len(entries) {
+			entries = entries[:n]
+		}
+	}
+	d.entries = d.entries[len(entries):]
+	return entries, nil
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string]*memFile{
+		".": {name: ".", mode: fs.ModeDir | 0555},
+	}}
+}
+
+/**
+ * Adds a regular file, replacing any earlier file with the same name, and
+ * the directories containing it.
+ */
+func (m *memFS) add(name string, data []byte, mode fs.FileMode, modTime time.Time) {
+	if f, ok := m.files[name]; ok {
+		if !f.IsDir() {
+			f.data, f.mode, f.modTime = data, mode, modTime
+		}
+		return
+	}
+	f := &memFile{name: path.Base(name), data: data, mode: mode, modTime: modTime}
+	m.files[name] = f
+	for name != "." {
+		dir := path.Dir(name)
+		parent, ok := m.files[dir]
+		if !ok {
+			parent = &memFile{name: path.Base(dir), mode: fs.ModeDir | 0555, modTime: modTime}
+			m.files[dir] = parent
+		}
+		parent.entries = append(parent.entries, f)
+		if ok {
+			return
+		}
+		name, f = dir, parent
+	}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if f.IsDir() {
+		return &openDir{f, f.sortedEntries()}, nil
+	}
+	return &openFile{bytes.NewReader(f.data), f}, nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	} else if !f.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return f.sortedEntries(), nil
+}
+
+// sortedEntries returns a copy of the entries of a directory sorted by name.
+func (f *memFile) sortedEntries() []fs.DirEntry {
+	entries := append([]fs.DirEntry(nil), f.entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
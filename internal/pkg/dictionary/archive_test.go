@@ -0,0 +1,139 @@
+package dictionary
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var archiveFiles = map[string]string{
+	"_wordclasses.txt":    "CLASS: GREET\nhello\nHi\n",
+	"_tones.txt":          "CLUSTER: C\nDIMENSION: D\nLAT: Greeting\n",
+	"Greeting.txt":        "!GREET there\nwelcome\n",
+	"sub/Uncertainty.txt": "I think\n",
+}
+
+// archiveNames returns the names of archiveFiles in an archive with the
+// given root directory, or none if root is "".
+func archiveNames(root string) map[string]string {
+	names := make(map[string]string)
+	for name, data := range archiveFiles {
+		names[path.Join(root, name)] = data
+	}
+	if root != "" {
+		// A metadata directory that should not be taken for the root.
+		names["__MACOSX/._Greeting.txt"] = ""
+	}
+	return names
+}
+
+func zipArchive(t *testing.T, root string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "dictionary.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range archiveNames(root) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func tarGzArchive(t *testing.T, root string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "dictionary.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, data := range archiveNames(root) {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestLoadArchive(t *testing.T) {
+	formats := map[string]func(*testing.T, string) string{
+		"zip":    zipArchive,
+		"tar.gz": tarGzArchive,
+	}
+	for format, create := range formats {
+		for _, root := range []string{"", "dictionary"} {
+			archive := create(t, root)
+			d, err := Load(archive)
+			if err != nil {
+				t.Fatalf("%s with root %q: %v", format, root, err)
+			}
+			directory := filepath.Join(archive, root)
+			if d.Directory != directory {
+				t.Errorf("%s with root %q: Expected the directory %s but instead got %s!",
+					format, root, directory, d.Directory)
+			}
+			lats := []Lat{
+				{"Greeting", filepath.Join(directory, "Greeting.txt"), "Greeting.txt"},
+				{"Uncertainty", filepath.Join(directory, "sub", "Uncertainty.txt"), "sub/Uncertainty.txt"},
+			}
+			if !reflect.DeepEqual(d.Lats, lats) {
+				t.Errorf("%s with root %q: Expected LATs %v but instead got %v!", format, root, lats, d.Lats)
+			}
+			if tones := d.Tones["C"]["D"]; !reflect.DeepEqual(tones, []string{"Greeting"}) {
+				t.Errorf("%s with root %q: Expected tones C/D to be [Greeting] but instead got %v!",
+					format, root, tones)
+			}
+			count := 0
+			if err := d.Walk(func(Pattern) error { count++; return nil }); err != nil {
+				t.Errorf("%s with root %q: %v", format, root, err)
+			} else if count != 3 {
+				t.Errorf("%s with root %q: Expected 3 patterns but instead got %d!", format, root, count)
+			}
+			if err := d.Close(); err != nil {
+				t.Errorf("%s with root %q: %v", format, root, err)
+			}
+		}
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	fsys := newMemFS()
+	for name, data := range archiveFiles {
+		fsys.add(path.Join("dictionary", name), []byte(data), 0644, time.Time{})
+	}
+	if err := fstest.TestFS(fsys, "dictionary/_wordclasses.txt", "dictionary/sub/Uncertainty.txt"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,277 @@
+/*
+Package dictionary reads a DocuScope dictionary directory.
+
+A dictionary directory contains a collection of LAT files, each named for
+its LAT with a .txt extension and containing one pattern per line, along
+with the special files _wordclasses.txt and (optionally) _tones.txt.
+The dictionary can also be read from a .zip or .tar.gz archive of the
+directory or from any fs.FS.
+*/
+package dictionary
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/fix"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/wordclasses"
+)
+
+// Names of the special files in a dictionary directory.
+const (
+	WordClassesFile = "_wordclasses.txt"
+	TonesFile       = "_tones.txt"
+)
+
+var patternRe = regexp.MustCompile(`[!?\w'-]+|[!"#$%&'()*+,-./:;<=>?@[\]^_\` + "`" + `{|}~]`)
+
+/**
+ * Splits a line from a LAT file into the words and word classes of a
+ * pattern with corrected letter case.
+ */
+func Tokenize(line string) []string {
+	return fix.Case(patternRe.FindAllString(line, -1))
+}
+
+// Lat is a LAT file in a dictionary.
+type Lat struct {
+	Name string
+	Path string // for messages, includes the dictionary path
+	file string // name in the dictionary file system
+}
+
+// Pattern is a single non-empty rule from a LAT file.
+type Pattern struct {
+	Lat   string
+	Words []string
+	Path  string
+	Line  int
+}
+
+/*
+Dictionary is a DocuScope dictionary.
+Classes are the word classes defined in _wordclasses.txt in file order.
+Words is a mapping of words to an array of the word and its classes.
+It starts with the contents of _wordclasses.txt and gains an entry for every
+word or class used in a pattern that is not otherwise defined as patterns
+are walked.
+Tones is nil if the dictionary does not have a _tones.txt file.
+*/
+type Dictionary struct {
+	Directory         string // the path of the dictionary for messages
+	fsys              fs.FS
+	Lats              []Lat
+	Classes           []wordclasses.Class
+	Words             map[string][]string
+	Tones             tones.Tones
+	DefaultWordsCount int
+	MissingWordsCount int
+	closer            io.Closer // the archive, if any
+}
+
+/**
+ * Loads the word classes, tones, and list of LAT files of the dictionary
+ * directory or archive at the given path (see Open).
+ * LAT patterns are read on demand with Walk or WalkLat.
+ * Errors reading the dictionary files are reported using the types in
+ * package dicterr.
+ * Close the dictionary when done to release an open archive.
+ */
+func Load(path string) (*Dictionary, error) {
+	fsys, name, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	closer, _ := fsys.(io.Closer)
+	d, err := LoadFS(fsys, name)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+	d.closer = closer
+	return d, nil
+}
+
+/**
+ * Closes the archive of a dictionary loaded with Load, if it has one.
+ * LAT patterns can not be read after the dictionary is closed.
+ */
+func (d *Dictionary) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+/**
+ * Loads the dictionary at the root of a file system.
+ *
+ * @param name: the path of the dictionary used in messages.
+ */
+func LoadFS(fsys fs.FS, name string) (*Dictionary, error) {
+	d := &Dictionary{
+		Directory: name,
+		fsys:      fsys,
+		Words:     make(map[string][]string),
+	}
+	classes, err := wordclasses.ReadClasses(fsys, WordClassesFile)
+	if err != nil {
+		return nil, d.locate(err)
+	}
+	d.Classes = classes
+	wordclasses.AddWords(d.Words, classes)
+	d.DefaultWordsCount = len(d.Words)
+
+	tonesPath := d.path(TonesFile)
+	if f, err := fsys.Open(TonesFile); err == nil {
+		d.Tones, err = tones.Read(f, tonesPath)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &dicterr.ReadError{Path: tonesPath, Err: err}
+	}
+
+	err = fs.WalkDir(fsys, ".", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return &dicterr.ReadError{Path: d.path(file), Err: err}
+		}
+		base := path.Base(file)
+		if !entry.IsDir() && path.Ext(file) == ".txt" &&
+			!strings.HasPrefix(base, "_") {
+			d.Lats = append(d.Lats, Lat{
+				Name: strings.TrimSuffix(base, ".txt"),
+				Path: d.path(file),
+				file: file,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+/**
+ * Parses the dictionary's _tones.txt file, keeping the line and kind of
+ * each listing for validation.
+ *
+ * @return a *dicterr.ReadError if the dictionary has no _tones.txt file.
+ */
+func (d *Dictionary) ParseTones() (*tones.File, error) {
+	tonesPath := d.path(TonesFile)
+	f, err := d.fsys.Open(TonesFile)
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: tonesPath, Err: err}
+	}
+	defer f.Close()
+	return tones.Parse(f, tonesPath)
+}
+
+// path returns the path of a file in the dictionary for messages.
+func (d *Dictionary) path(file string) string {
+	return filepath.Join(d.Directory, filepath.FromSlash(file))
+}
+
+/**
+ * Replaces the file system name in the path of an error from reading a
+ * dictionary file with its path for messages.
+ */
+func (d *Dictionary) locate(err error) error {
+	var missing *dicterr.MissingWordClassesError
+	var read *dicterr.ReadError
+	var long *dicterr.LineTooLongError
+	switch {
+	case errors.As(err, &missing):
+		missing.Path = d.path(missing.Path)
+	case errors.As(err, &read):
+		read.Path = d.path(read.Path)
+	case errors.As(err, &long):
+		long.Path = d.path(long.Path)
+	}
+	return err
+}
+
+/**
+ * Calls fn for every pattern in every LAT file of the dictionary in order.
+ */
+func (d *Dictionary) Walk(fn func(Pattern) error) error {
+	for _, lat := range d.Lats {
+		if err := d.WalkLat(lat, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * Calls fn for every pattern in the given LAT file.
+ * Words used in the patterns that are not already known are added to
+ * Words and counted in MissingWordsCount.
+ * Walking stops at the first error returned by fn.
+ */
+func (d *Dictionary) WalkLat(lat Lat, fn func(Pattern) error) error {
+	content, err := d.fsys.Open(lat.file)
+	if err != nil {
+		return &dicterr.ReadError{Path: lat.Path, Err: err}
+	}
+	defer content.Close()
+
+	scanner := bufio.NewScanner(content)
+	line := 0
+	for scanner.Scan() {
+		line++
+		words := Tokenize(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		for _, w := range words {
+			if wds, ok := d.Words[w]; !ok {
+				d.Words[w] = append(wds, w)
+				d.MissingWordsCount++
+			}
+		}
+		if err := fn(Pattern{lat.Name, words, lat.Path, line}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return dicterr.Scan(lat.Path, line+1, err)
+	}
+	if err := content.Close(); err != nil {
+		return &dicterr.ReadError{Path: lat.Path, Err: err}
+	}
+	return nil
+}
+
+/**
+ * Returns the hex SHA-256 hash of the contents of the given LAT file, so
+ * that a change to the file can be detected.
+ */
+func (d *Dictionary) HashLat(lat Lat) (string, error) {
+	content, err := d.fsys.Open(lat.file)
+	if err != nil {
+		return "", &dicterr.ReadError{Path: lat.Path, Err: err}
+	}
+	defer content.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", &dicterr.ReadError{Path: lat.Path, Err: err}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
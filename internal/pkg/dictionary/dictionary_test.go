@@ -0,0 +1,78 @@
+package dictionary
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+func TestTokenize(t *testing.T) {
+	test := "I can't !greet, you-all?"
+	expected := []string{"i", "can't", "!GREET", ",", "you-all?"}
+	actual := Tokenize(test)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q to tokenize to %q but instead got %q!", test, expected, actual)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_wordclasses.txt":    {Data: []byte("CLASS: GREET\nhello\nHi\n")},
+		"_tones.txt":          {Data: []byte("CLUSTER: C\nDIMENSION: D\nLAT: Greeting\n")},
+		"Greeting.txt":        {Data: []byte("!GREET there\n\nwelcome\n")},
+		"sub/Uncertainty.txt": {Data: []byte("I think\n")},
+		"notes.md":            {Data: []byte("not a LAT\n")},
+	}
+	d, err := LoadFS(fsys, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lats := []Lat{
+		{"Greeting", filepath.Join("test", "Greeting.txt"), "Greeting.txt"},
+		{"Uncertainty", filepath.Join("test", "sub", "Uncertainty.txt"), "sub/Uncertainty.txt"},
+	}
+	if !reflect.DeepEqual(d.Lats, lats) {
+		t.Errorf("Expected LATs %v but instead got %v!", lats, d.Lats)
+	}
+	if tones := d.Tones["C"]["D"]; !reflect.DeepEqual(tones, []string{"Greeting"}) {
+		t.Errorf("Expected tones C/D to be [Greeting] but instead got %v!", tones)
+	}
+
+	var patterns []string
+	err = d.Walk(func(p Pattern) error {
+		patterns = append(patterns, fmt.Sprintf("%s:%d %s %v", p.Lat, p.Line, p.Path, p.Words))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Greeting:1 " + filepath.Join("test", "Greeting.txt") + " [!GREET there]",
+		"Greeting:3 " + filepath.Join("test", "Greeting.txt") + " [welcome]",
+		"Uncertainty:1 " + filepath.Join("test", "sub", "Uncertainty.txt") + " [i think]",
+	}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("Expected patterns %q but instead got %q!", expected, patterns)
+	}
+	// hello and hi from _wordclasses.txt; !GREET, there, welcome, i, and think from the patterns.
+	if d.DefaultWordsCount != 2 || d.MissingWordsCount != 5 {
+		t.Errorf("Expected 2 default and 5 missing words but instead got %d and %d!",
+			d.DefaultWordsCount, d.MissingWordsCount)
+	}
+}
+
+func TestLoadFSMissingWordClasses(t *testing.T) {
+	_, err := LoadFS(fstest.MapFS{"Greeting.txt": {Data: []byte("hello\n")}}, "test")
+	var missing *dicterr.MissingWordClassesError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingWordClassesError but instead got %v!", err)
+	} else if expected := filepath.Join("test", WordClassesFile); missing.Path != expected {
+		t.Errorf("Expected the error path %s but instead got %s!", expected, missing.Path)
+	}
+}
@@ -0,0 +1,19 @@
+package fix
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCase(t *testing.T) {
+	test := []string{"I", "Think", "!greet", "!Bang", "can't", ","}
+	expected := []string{"i", "think", "!GREET", "!BANG", "can't", ","}
+	actual := Case(test)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q to be corrected to %q but instead got %q!", test, expected, actual)
+	}
+	if test[0] != "I" {
+		t.Errorf("Expected the pattern to be unchanged but instead got %q!", test)
+	}
+}
@@ -0,0 +1,52 @@
+package golden
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Writes the files under dir to a zip archive in a temporary directory
+ * that is removed when the test finishes.
+ *
+ * @param root: a directory to wrap the files in, or "" for none.
+ * @return the path of the archive.
+ */
+func Zip(t *testing.T, dir string, root string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "dictionary.zip")
+	out, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	err = fs.WalkDir(os.DirFS(dir), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		w, err := zw.Create(path.Join(root, name))
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(w, in)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
@@ -0,0 +1,75 @@
+/*
+Package golden compares command output in tests to golden files.
+
+Run the tests with -update to rewrite the golden files after an intended
+change in output:
+
+	go test ./cmd/... -update
+
+The shared miniature dictionary used by the command tests is in
+testdata/dictionary at the top of the repository.
+*/
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the actual output")
+
+/**
+ * Fails the test if actual differs from the contents of the golden file.
+ *
+ * @param path: the golden file, usually testdata/<name>.golden.
+ */
+func Assert(t *testing.T, path string, actual []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Output does not match %s (run with -update if the change is intended)\nexpected:\n%s\nactual:\n%s",
+			path, expected, actual)
+	}
+}
+
+/**
+ * Runs fn and returns everything it writes to standard output along with
+ * the error it returns.
+ */
+func Stdout(t *testing.T, fn func() error) ([]byte, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		output <- b
+	}()
+	err = fn()
+	w.Close()
+	b := <-output
+	r.Close()
+	return b, err
+}
@@ -0,0 +1,191 @@
+/*
+Package output writes command output to standard output or to a file with
+optional gzip or zstd compression.
+
+Files are written to a temporary file in the same directory which is only
+renamed to the final path once everything has been written, so an
+interrupted run never leaves a truncated file behind.
+*/
+package output
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+// Compression is the compression applied to the output.
+type Compression string
+
+const (
+	Auto Compression = "auto" // from the file extension
+	None Compression = "none"
+	Gzip Compression = "gzip"
+	Zstd Compression = "zstd"
+)
+
+// Compressions lists the accepted values of --compress.
+var Compressions = []Compression{Auto, None, Gzip, Zstd}
+
+// gzipBlockSize is the size of the blocks compressed in parallel.
+const gzipBlockSize = 1 << 20
+
+/**
+ * Returns the Compression named by s.
+ */
+func ParseCompression(s string) (Compression, error) {
+	for _, c := range Compressions {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown compression %q, expected one of %v", s, Compressions)
+}
+
+/**
+ * Infers the compression from the extension of the path: .gz for gzip,
+ * .zst for zstd, otherwise none.
+ */
+func Infer(path string) Compression {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip":
+		return Gzip
+	case ".zst", ".zstd":
+		return Zstd
+	}
+	return None
+}
+
+// File is an output destination.  It must be closed to commit the output.
+type File struct {
+	Path       string // the final path, "stdout" for standard output
+	w          io.Writer
+	compressor io.WriteCloser
+	temp       *os.File
+	done       bool
+}
+
+/**
+ * Opens the output for writing.
+ *
+ * @param path: the output file, standard output if empty or "-".
+ * @param compression: Auto infers the compression from the extension of
+ *   path and is no compression for standard output.
+ */
+func Create(path string, compression Compression) (*File, error) {
+	f := &File{Path: path}
+	var dest io.Writer
+	if path == "" || path == "-" {
+		f.Path = "stdout"
+		if compression == Auto {
+			compression = None
+		}
+		dest = os.Stdout
+	} else {
+		if compression == Auto {
+			compression = Infer(path)
+		}
+		dir, base := filepath.Split(filepath.Clean(path))
+		if dir == "" {
+			dir = "."
+		}
+		temp, err := ioutil.TempFile(dir, "."+base+".*.tmp")
+		if err != nil {
+			return nil, &dicterr.WriteError{Path: path, Err: err}
+		}
+		f.temp = temp
+		dest = temp
+	}
+
+	switch compression {
+	case Gzip:
+		gz := pgzip.NewWriter(dest)
+		if err := gz.SetConcurrency(gzipBlockSize, runtime.GOMAXPROCS(0)); err != nil {
+			f.Abort()
+			return nil, err
+		}
+		f.compressor = gz
+	case Zstd:
+		zw, err := zstd.NewWriter(dest, zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0)))
+		if err != nil {
+			f.Abort()
+			return nil, err
+		}
+		f.compressor = zw
+	}
+	if f.compressor != nil {
+		f.w = f.compressor
+	} else {
+		f.w = dest
+	}
+	return f, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+/**
+ * Finishes the compression and moves the temporary file to the final path.
+ * Nothing is written to the final path if any step fails.
+ */
+func (f *File) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	var err error
+	if f.compressor != nil {
+		err = f.compressor.Close()
+	}
+	if f.temp == nil {
+		if err != nil {
+			return &dicterr.WriteError{Path: f.Path, Err: err}
+		}
+		return nil
+	}
+	if err == nil {
+		err = f.temp.Sync()
+	}
+	if cerr := f.temp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.temp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.temp.Name(), f.Path)
+	}
+	if err != nil {
+		os.Remove(f.temp.Name())
+		return &dicterr.WriteError{Path: f.Path, Err: err}
+	}
+	return nil
+}
+
+/**
+ * Discards the output, removing the temporary file.  Does nothing if the
+ * file has already been closed so it can be deferred.
+ */
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	if f.temp != nil {
+		if f.compressor != nil {
+			f.compressor.Close() // stops the compression goroutines
+		}
+		f.temp.Close()
+		os.Remove(f.temp.Name())
+	}
+}
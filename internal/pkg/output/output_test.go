@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+// tempFiles lists the temporary files left in dir.
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestCloseRenames(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	f, err := Create(path, Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no %s before Close but instead got %v!", path, err)
+	}
+	if temps := tempFiles(t, dir); len(temps) != 1 {
+		t.Errorf("Expected one temporary file before Close but instead got %v!", temps)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{}" {
+		t.Errorf("Expected {} in %s but instead got %q!", path, b)
+	}
+	if temps := tempFiles(t, dir); len(temps) != 0 {
+		t.Errorf("Expected no temporary files after Close but instead got %v!", temps)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Create(path, Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "old" {
+		t.Errorf("Expected Abort to leave %s untouched but instead it has %q!", path, b)
+	}
+	if temps := tempFiles(t, dir); len(temps) != 0 {
+		t.Errorf("Expected no temporary files after Abort but instead got %v!", temps)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Expected Close after Abort to do nothing but instead got %v!", err)
+	}
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	dict := &rules.DocuScopeDictionary{
+		Rules:      make(rules.RulesMap),
+		ShortRules: rules.NewShortRules(rules.LastWins),
+		Words:      map[string][]string{"hello": {"hello", "!GREET"}},
+	}
+	rules.Add(dict.Rules, "Greeting", []string{"!GREET", "there"})
+	expected, err := json.Marshal(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	magic := map[string][]byte{
+		"rules.json.gz":  {0x1f, 0x8b},
+		"rules.json.zst": {0x28, 0xb5, 0x2f, 0xfd},
+	}
+	for name, prefix := range magic {
+		path := filepath.Join(t.TempDir(), name)
+		f, err := Create(path, Auto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b, prefix) {
+			t.Errorf("Expected %s to start with the magic number %x!", name, prefix)
+		}
+		read, err := rules.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		actual, err := json.Marshal(read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s: Expected %s but instead got %s!", name, expected, actual)
+		}
+	}
+}
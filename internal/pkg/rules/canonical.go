@@ -0,0 +1,62 @@
+package rules
+
+import (
+	"sort"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/wordclasses"
+)
+
+/**
+ * Sorts the arrays in the dictionary so that its JSON only depends on the
+ * patterns and word classes and not on the order of the LAT files or of
+ * the lines in them.
+ *   - The patterns of each LAT under a first and second word are sorted by
+ *     comparePatterns.
+ *   - With the KeepAll policy, the LATs of each shortRules word are sorted.
+ *   - The classes of each word are sorted as by wordclasses.Sort.
+ * Object keys are always sorted by encoding/json.
+ */
+func (d *DocuScopeDictionary) Sort() {
+	for _, seconds := range d.Rules {
+		for _, lats := range seconds {
+			for _, patterns := range lats {
+				sort.Slice(patterns, func(i, j int) bool {
+					return comparePatterns(patterns[i], patterns[j]) < 0
+				})
+			}
+		}
+	}
+	d.ShortRules.Sort()
+	wordclasses.Sort(d.Words)
+}
+
+/**
+ * Sorts the LATs of each word when using the KeepAll policy.  The other
+ * policies have already selected a single LAT for each word.
+ */
+func (s *ShortRules) Sort() {
+	if s.Policy != KeepAll {
+		return
+	}
+	for _, rule := range s.words {
+		sort.Strings(rule.lats)
+	}
+}
+
+/**
+ * Compares patterns word by word in byte order, a pattern that is a prefix
+ * of the other sorts first.
+ *
+ * @return negative if a sorts before b, 0 if equal, positive otherwise.
+ */
+func comparePatterns(a []string, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
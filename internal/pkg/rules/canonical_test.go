@@ -0,0 +1,62 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+func TestCanonicalIsOrderIndependent(t *testing.T) {
+	// The same dictionary with LATs in different files and lines in different orders.
+	dictionaries := []fstest.MapFS{
+		{
+			"_wordclasses.txt": {Data: []byte("CLASS: A\nhi\n\nCLASS: B\nhi\n")},
+			"X.txt":            {Data: []byte("i think so\ni think\nwow\ni think it\n")},
+			"Y.txt":            {Data: []byte("wow\ni think that\n")},
+		},
+		{
+			"_wordclasses.txt": {Data: []byte("CLASS: B\nhi\n\nCLASS: A\nhi\n")},
+			"a/Y.txt":          {Data: []byte("i think that\nwow\n")},
+			"X.txt":            {Data: []byte("i think it\nwow\ni think\ni think so\n")},
+		},
+	}
+	var outputs [][]byte
+	for _, fsys := range dictionaries {
+		d, err := dictionary.LoadFS(fsys, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dict, err := Build(d, KeepAll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dict.Sort()
+		b, err := json.Marshal(dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, b)
+
+		d, err = dictionary.LoadFS(fsys, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var streamed bytes.Buffer
+		if _, err := Stream(&streamed, "streamed", d, KeepAll, 2, "", true); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, streamed.Bytes())
+	}
+
+	expected := `{"rules":{"i":{"think":{"X":[[],["it"],["so"]],"Y":[["that"]]}}},` +
+		`"shortRules":{"wow":["X","Y"]},` +
+		`"words":{"hi":["hi","!A","!B"],"i":["i"],"it":["it"],"so":["so"],"that":["that"],"think":["think"],"wow":["wow"]}}`
+	for i, output := range outputs {
+		if string(output) != expected {
+			t.Errorf("Expected canonical output %d to be %s but instead got %s!", i, expected, output)
+		}
+	}
+}
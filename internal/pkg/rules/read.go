@@ -0,0 +1,72 @@
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+/**
+ * Reads the, possibly gzip or zstd compressed, JSON output of
+ * docuscope-rules.
+ *
+ * @param path: used for error reporting.
+ * @return a *dicterr.FormatError if the content is not rules JSON or a
+ *   *dicterr.ReadError if it can not be read.
+ */
+func Read(r io.Reader, path string) (*DocuScopeDictionary, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	var dict DocuScopeDictionary
+	err = json.NewDecoder(r).Decode(&dict)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return nil, &dicterr.FormatError{Path: path, Err: err}
+	} else if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	if dict.ShortRules == nil {
+		dict.ShortRules = NewShortRules(LastWins)
+	}
+	return &dict, nil
+}
+
+/**
+ * Reads the rules JSON file at the given path.
+ */
+func ReadFile(path string) (*DocuScopeDictionary, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	defer file.Close()
+	return Read(file, path)
+}
+
+/**
+ * Returns a reader for the uncompressed content of r, which is detected
+ * as gzip or zstd compressed by its magic number.
+ */
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return zstd.NewReader(br)
+	}
+	return br, nil
+}
@@ -0,0 +1,73 @@
+/*
+Package rules builds the reverse lookup rules used by the DocuScope tagger
+from a dictionary.
+
+JSON schema: See api/docuscope_rules_schema.json
+*/
+package rules
+
+import (
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+type RulesMap map[string]map[string]map[string][][]string
+
+/*
+DocuScopeDictionary contains the patterns and words in the dictionary used by
+DocuScope to parse a document.
+The rules are organized such that it is a partial reverse lookup with the
+initial bigram serving as the indicies to an array of arrays where the first
+element is the id of the LAT and the second is an array with the full pattern.
+The ShortRules are a mapping of unigram to LAT id, or LAT ids when using the
+KeepAll policy.
+Words is a mapping of !CLASS or words to an array of words or classes.
+*/
+type DocuScopeDictionary struct {
+	Rules      RulesMap            `json:"rules"`
+	ShortRules *ShortRules         `json:"shortRules"`
+	Words      map[string][]string `json:"words"`
+}
+
+/**
+ * Add a pattern to the rules map.
+ */
+func Add(m RulesMap, lat string, rule []string) {
+	mm, ok := m[rule[0]]
+	if !ok {
+		mm = make(map[string]map[string][][]string)
+		m[rule[0]] = mm
+	}
+	mmm, ok := mm[rule[1]]
+	if !ok {
+		mmm = make(map[string][][]string)
+		mm[rule[1]] = mmm
+	}
+	mmm[lat] = append(mmm[lat], rule[2:])
+}
+
+/**
+ * Generates the rules for all of the patterns in the dictionary.
+ * Single word patterns are added to the ShortRules using the given policy.
+ * With the Error policy, a *ConflictError is returned if there are any
+ * conflicts along with the rules so that the conflicts can be reported.
+ */
+func Build(d *dictionary.Dictionary, policy Policy) (*DocuScopeDictionary, error) {
+	rules := make(RulesMap)
+	shortRules := NewShortRules(policy)
+	err := d.Walk(func(p dictionary.Pattern) error {
+		if len(p.Words) == 1 {
+			shortRules.Add(p.Words[0], p.Lat)
+		} else {
+			Add(rules, p.Lat, p.Words)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &DocuScopeDictionary{rules, shortRules, d.Words}
+	if conflicts := shortRules.Conflicts(); policy == Error && len(conflicts) > 0 {
+		return result, &ConflictError{conflicts}
+	}
+	return result, nil
+}
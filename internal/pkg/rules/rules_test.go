@@ -0,0 +1,24 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	m := make(RulesMap)
+	Add(m, "Confidence", []string{"i", "think"})
+	Add(m, "Uncertainty", []string{"i", "think", "so"})
+	Add(m, "Uncertainty", []string{"i", "think", "it", "might"})
+	Add(m, "Confidence", []string{"of", "course"})
+	expected := RulesMap{
+		"i": {"think": {
+			"Confidence":  {{}},
+			"Uncertainty": {{"so"}, {"it", "might"}},
+		}},
+		"of": {"course": {"Confidence": {{}}}},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Expected rules %v but instead got %v!", expected, m)
+	}
+}
@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Policy for resolving single word patterns that are used by multiple LATs.
+type Policy string
+
+const (
+	Error     Policy = "error"      // Fail if there are any conflicts.
+	FirstWins Policy = "first-wins" // Use the first LAT in dictionary order.
+	LastWins  Policy = "last-wins"  // Use the last LAT in dictionary order.
+	KeepAll   Policy = "keep-all"   // Map the word to the list of all LATs.
+)
+
+// Policies lists the valid policies.
+var Policies = []Policy{Error, FirstWins, LastWins, KeepAll}
+
+/**
+ * Converts a policy name to a Policy.
+ */
+func ParsePolicy(name string) (Policy, error) {
+	for _, p := range Policies {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown shortRules policy %q", name)
+}
+
+/*
+ShortRules is the mapping of unigram to LAT for single word patterns.
+Every LAT that uses a word is recorded so that conflicts can be reported.
+When marshaled to JSON, words map to the LAT chosen by the policy, or to
+the list of all LATs for the KeepAll policy.
+*/
+type ShortRules struct {
+	Policy Policy
+	words  map[string]*shortRule
+}
+
+type shortRule struct {
+	lats []string // in order of first use, without duplicates
+	last string
+}
+
+// Conflict is a word used as a single word pattern by multiple LATs.
+type Conflict struct {
+	Word string   `json:"word"`
+	Lats []string `json:"lats"`
+}
+
+// ConflictError reports the conflicts found when using the Error policy.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d single word patterns are used by multiple LATs", len(e.Conflicts))
+}
+
+// BadInput marks the conflicts as a problem with the dictionary.
+func (e *ConflictError) BadInput() bool { return true }
+
+func NewShortRules(policy Policy) *ShortRules {
+	return &ShortRules{policy, make(map[string]*shortRule)}
+}
+
+/**
+ * Records that lat has the single word pattern word.
+ */
+func (s *ShortRules) Add(word string, lat string) {
+	rule, ok := s.words[word]
+	if !ok {
+		rule = &shortRule{}
+		s.words[word] = rule
+	}
+	rule.last = lat
+	for _, l := range rule.lats {
+		if l == lat {
+			return
+		}
+	}
+	rule.lats = append(rule.lats, lat)
+}
+
+/**
+ * Returns the LATs for the word as selected by the policy.
+ * This is all of the LATs for the KeepAll policy and at most one otherwise.
+ */
+func (s *ShortRules) Get(word string) []string {
+	rule, ok := s.words[word]
+	switch {
+	case !ok:
+		return nil
+	case s.Policy == KeepAll:
+		return rule.lats
+	case s.Policy == FirstWins:
+		return rule.lats[:1]
+	default:
+		return []string{rule.last}
+	}
+}
+
+// Len is the number of words with short rules.
+func (s *ShortRules) Len() int {
+	return len(s.words)
+}
+
+// Words lists the words with short rules in sorted order.
+func (s *ShortRules) Words() []string {
+	words := make([]string, 0, len(s.words))
+	for word := range s.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+/**
+ * Lists the words used by more than one LAT, sorted by word.
+ */
+func (s *ShortRules) Conflicts() []Conflict {
+	var conflicts []Conflict
+	for word, rule := range s.words {
+		if len(rule.lats) > 1 {
+			conflicts = append(conflicts, Conflict{word, rule.lats})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Word < conflicts[j].Word
+	})
+	return conflicts
+}
+
+func (s *ShortRules) MarshalJSON() ([]byte, error) {
+	if s.Policy == KeepAll {
+		m := make(map[string][]string, len(s.words))
+		for word := range s.words {
+			m[word] = s.Get(word)
+		}
+		return json.Marshal(m)
+	}
+	m := make(map[string]string, len(s.words))
+	for word := range s.words {
+		m[word] = s.Get(word)[0]
+	}
+	return json.Marshal(m)
+}
+
+/**
+ * Reads short rules in either the single LAT or list of LATs form.
+ * The policy is KeepAll if any word has a list of LATs and LastWins
+ * otherwise.
+ */
+func (s *ShortRules) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	s.Policy = LastWins
+	s.words = make(map[string]*shortRule, len(m))
+	for word, raw := range m {
+		var lats []string
+		if err := json.Unmarshal(raw, &lats); err == nil {
+			s.Policy = KeepAll
+		} else {
+			var lat string
+			if err := json.Unmarshal(raw, &lat); err != nil {
+				return fmt.Errorf("shortRules %q: %w", word, err)
+			}
+			lats = []string{lat}
+		}
+		for _, lat := range lats {
+			s.Add(word, lat)
+		}
+	}
+	return nil
+}
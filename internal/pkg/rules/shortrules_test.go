@@ -0,0 +1,40 @@
+package rules
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestShortRulesPolicies(t *testing.T) {
+	expected := map[Policy]string{
+		FirstWins: `{"a":"X","b":"Y"}`,
+		LastWins:  `{"a":"Y","b":"Y"}`,
+		KeepAll:   `{"a":["X","Y"],"b":["Y"]}`,
+	}
+	for policy, want := range expected {
+		s := NewShortRules(policy)
+		s.Add("a", "X")
+		s.Add("a", "Y")
+		s.Add("b", "Y")
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("Expected %s to marshal to %s but instead got %s!", policy, want, b)
+		}
+		conflicts := []Conflict{{"a", []string{"X", "Y"}}}
+		if actual := s.Conflicts(); !reflect.DeepEqual(actual, conflicts) {
+			t.Errorf("Expected conflicts %v but instead got %v!", conflicts, actual)
+		}
+
+		var read ShortRules
+		if err := json.Unmarshal(b, &read); err != nil {
+			t.Fatal(err)
+		}
+		if actual := read.Get("a"); !reflect.DeepEqual(actual, s.Get("a")) {
+			t.Errorf("Expected %s round trip to give %v but instead got %v!", policy, s.Get("a"), actual)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package rules
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/wordclasses"
+)
+
+// DefaultRunSize is the default number of patterns sorted in memory by Stream.
+const DefaultRunSize = 1000000
+
+// record is a pattern of two or more words in the order it is output.
+type record struct {
+	First  string
+	Second string
+	Lat    string
+	Seq    int64 // order in the dictionary, keeps patterns of a LAT in order
+	Rest   []string
+}
+
+// In canonical order all records have the same Seq so Rest decides.
+
+func (a *record) less(b *record) bool {
+	switch {
+	case a.First != b.First:
+		return a.First < b.First
+	case a.Second != b.Second:
+		return a.Second < b.Second
+	case a.Lat != b.Lat:
+		return a.Lat < b.Lat
+	case a.Seq != b.Seq:
+		return a.Seq < b.Seq
+	}
+	return comparePatterns(a.Rest, b.Rest) < 0
+}
+
+/**
+ * Writes the same JSON as marshaling the DocuScopeDictionary from Build, but
+ * without holding all of the rules in memory.
+ * Patterns are sorted in runs of at most runSize patterns which are saved to
+ * temporary files in tempDir (the system default if empty) and then merged
+ * while writing the rules.  The ShortRules and Words are kept in memory as
+ * they are much smaller.
+ * With the Error policy, nothing is written if there are conflicts.
+ * If canonical, the output is the same as for a DocuScopeDictionary after
+ * Sort.
+ *
+ * @param name: the name of w for write errors, eg. its path or "stdout".
+ * @return the ShortRules for conflict reporting.
+ */
+func Stream(w io.Writer, name string, d *dictionary.Dictionary, policy Policy, runSize int, tempDir string, canonical bool) (*ShortRules, error) {
+	if runSize <= 0 {
+		runSize = DefaultRunSize
+	}
+	shortRules := NewShortRules(policy)
+	var runs []string
+	defer func() {
+		for _, run := range runs {
+			os.Remove(run)
+		}
+	}()
+
+	var seq int64
+	buffer := make([]record, 0, runSize)
+	err := d.Walk(func(p dictionary.Pattern) error {
+		if len(p.Words) == 1 {
+			shortRules.Add(p.Words[0], p.Lat)
+			return nil
+		}
+		buffer = append(buffer, record{p.Words[0], p.Words[1], p.Lat, seq, p.Words[2:]})
+		if !canonical {
+			seq++
+		}
+		if len(buffer) == runSize {
+			run, err := writeRun(buffer, tempDir)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			buffer = buffer[:0]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if conflicts := shortRules.Conflicts(); policy == Error && len(conflicts) > 0 {
+		return shortRules, &ConflictError{conflicts}
+	}
+	if canonical {
+		shortRules.Sort()
+		wordclasses.Sort(d.Words)
+	}
+
+	sortRecords(buffer)
+	sources := []source{&sliceSource{records: buffer}}
+	for _, run := range runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return nil, &dicterr.ReadError{Path: run, Err: err}
+		}
+		defer f.Close()
+		sources = append(sources, &runSource{path: run, decoder: gob.NewDecoder(bufio.NewReader(f))})
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeRules(bw, name, sources); err != nil {
+		return nil, err
+	}
+	if err := writeTail(bw, name, shortRules, d.Words); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, &dicterr.WriteError{Path: name, Err: err}
+	}
+	return shortRules, nil
+}
+
+func sortRecords(records []record) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].less(&records[j])
+	})
+}
+
+/**
+ * Sorts the records and saves them to a temporary file.
+ */
+func writeRun(records []record, tempDir string) (string, error) {
+	sortRecords(records)
+	f, err := ioutil.TempFile(tempDir, "docuscope-rules-*.run")
+	if err != nil {
+		dir := tempDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		return "", &dicterr.WriteError{Path: dir, Err: err}
+	}
+	bw := bufio.NewWriter(f)
+	encoder := gob.NewEncoder(bw)
+	for i := range records {
+		if err = encoder.Encode(&records[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", &dicterr.WriteError{Path: f.Name(), Err: err}
+	}
+	return f.Name(), nil
+}
+
+// source is a sorted sequence of records.
+type source interface {
+	// next returns the next record or nil at the end.
+	next() (*record, error)
+}
+
+type sliceSource struct {
+	records []record
+	index   int
+}
+
+func (s *sliceSource) next() (*record, error) {
+	if s.index == len(s.records) {
+		return nil, nil
+	}
+	s.index++
+	return &s.records[s.index-1], nil
+}
+
+type runSource struct {
+	path    string
+	decoder *gob.Decoder
+}
+
+func (s *runSource) next() (*record, error) {
+	var r record
+	err := s.decoder.Decode(&r)
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, &dicterr.ReadError{Path: s.path, Err: err}
+	}
+	return &r, nil
+}
+
+// head is the current record of a source in the merge.
+type head struct {
+	record *record
+	source source
+}
+
+type mergeHeap []head
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].record.less(h[j].record) }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(head)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+/**
+ * Merges the sources writing the "rules" object grouped by first word,
+ * second word, and LAT.
+ */
+func writeRules(w *bufio.Writer, name string, sources []source) error {
+	h := make(mergeHeap, 0, len(sources))
+	for _, s := range sources {
+		r, err := s.next()
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			h = append(h, head{r, s})
+		}
+	}
+	heap.Init(&h)
+
+	if _, err := w.WriteString(`{"rules":{`); err != nil {
+		return &dicterr.WriteError{Path: name, Err: err}
+	}
+	var prev *record
+	for h.Len() > 0 {
+		r := h[0].record
+		if err := writeRecord(w, prev, r); err != nil {
+			return &dicterr.WriteError{Path: name, Err: err}
+		}
+		prev = r
+		next, err := h[0].source.next()
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			h[0].record = next
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	if prev != nil {
+		if _, err := w.WriteString("]}}"); err != nil {
+			return &dicterr.WriteError{Path: name, Err: err}
+		}
+	}
+	if _, err := w.WriteString("}"); err != nil {
+		return &dicterr.WriteError{Path: name, Err: err}
+	}
+	return nil
+}
+
+/**
+ * Writes the record, opening and closing objects when its words or LAT
+ * differ from the previous record.
+ */
+func writeRecord(w *bufio.Writer, prev *record, r *record) error {
+	switch {
+	case prev == nil:
+		writeKey(w, r.First, "{")
+		writeKey(w, r.Second, "{")
+		writeKey(w, r.Lat, "[")
+	case prev.First != r.First:
+		w.WriteString("]}},")
+		writeKey(w, r.First, "{")
+		writeKey(w, r.Second, "{")
+		writeKey(w, r.Lat, "[")
+	case prev.Second != r.Second:
+		w.WriteString("]},")
+		writeKey(w, r.Second, "{")
+		writeKey(w, r.Lat, "[")
+	case prev.Lat != r.Lat:
+		w.WriteString("],")
+		writeKey(w, r.Lat, "[")
+	default:
+		w.WriteString(",")
+	}
+	rest := r.Rest
+	if rest == nil {
+		rest = []string{}
+	}
+	b, err := json.Marshal(rest)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func writeKey(w *bufio.Writer, key string, open string) {
+	b, _ := json.Marshal(key)
+	w.Write(b)
+	w.WriteString(":")
+	w.WriteString(open)
+}
+
+/**
+ * Writes the "shortRules" and "words" and closes the dictionary object.
+ */
+func writeTail(w *bufio.Writer, name string, shortRules *ShortRules, words map[string][]string) error {
+	s, err := json.Marshal(shortRules)
+	if err != nil {
+		return err
+	}
+	wd, err := json.Marshal(words)
+	if err != nil {
+		return err
+	}
+	w.WriteString(`,"shortRules":`)
+	w.Write(s)
+	w.WriteString(`,"words":`)
+	w.Write(wd)
+	if _, err := w.WriteString("}"); err != nil {
+		return &dicterr.WriteError{Path: name, Err: err}
+	}
+	return nil
+}
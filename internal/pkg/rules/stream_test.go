@@ -0,0 +1,44 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+func TestStreamMatchesMarshal(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_wordclasses.txt": {Data: []byte("CLASS: GREET\nhello\nhi\n")},
+		"Confidence.txt":   {Data: []byte("I think\nI believe that\n!GREET there\nwow\n")},
+		"Uncertainty.txt":  {Data: []byte("wow\nI think so\nmaybe , perhaps\nI believe\n")},
+	}
+	d, err := dictionary.LoadFS(fsys, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := Build(d, KeepAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.Marshal(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, runSize := range []int{1, 2, 100} {
+		d, err := dictionary.LoadFS(fsys, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := Stream(&b, "output", d, KeepAll, runSize, "", false); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Errorf("Expected run size %d to stream %s but instead got %s!", runSize, expected, b.Bytes())
+		}
+	}
+}
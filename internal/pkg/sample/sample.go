@@ -0,0 +1,37 @@
+/*
+Package sample embeds a small DocuScope dictionary in the binary for
+examples and tests.
+
+The dictionary has three LATs, Certainty, Hedges, and Greetings, the word
+classes !GREETING and !MODAL, and a _tones.txt that assigns each LAT to a
+dimension.
+*/
+package sample
+
+import (
+	"embed"
+	"io/fs"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+)
+
+//go:embed dictionary/*.txt
+var files embed.FS // the pattern matches the _ files which a directory would not
+
+/**
+ * Returns the file system with the sample dictionary at its root.
+ */
+func FS() fs.FS {
+	sub, err := fs.Sub(files, "dictionary")
+	if err != nil {
+		panic(err) // "dictionary" is always a valid path
+	}
+	return sub
+}
+
+/**
+ * Loads the sample dictionary.
+ */
+func Load() (*dictionary.Dictionary, error) {
+	return dictionary.LoadFS(FS(), "sample")
+}
@@ -0,0 +1,41 @@
+package sample
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/tones"
+)
+
+func TestLoad(t *testing.T) {
+	d, err := dictionary.LoadFS(FS(), "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lats []string
+	for _, lat := range d.Lats {
+		lats = append(lats, lat.Name)
+	}
+	if expected := []string{"Certainty", "Greetings", "Hedges"}; !reflect.DeepEqual(lats, expected) {
+		t.Errorf("Expected LATs %v but instead got %v!", expected, lats)
+	}
+	if len(d.Classes) != 2 {
+		t.Errorf("Expected 2 word classes but instead got %d!", len(d.Classes))
+	}
+	expected := tones.Tones{
+		"Confidence": {"Certainty": {"Certainty"}, "Uncertainty": {"Hedges"}},
+		"Relations":  {"Interactive": {"Greetings"}},
+	}
+	if !reflect.DeepEqual(d.Tones, expected) {
+		t.Errorf("Expected tones %v but instead got %v!", expected, d.Tones)
+	}
+
+	count := 0
+	if err := d.Walk(func(dictionary.Pattern) error { count++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("Expected 10 patterns but instead got %d!", count)
+	}
+}
@@ -0,0 +1,71 @@
+package tagger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dictionary"
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+/**
+ * Returns a Tagger for either a dictionary directory or archive or a,
+ * possibly gzip or zstd compressed, JSON rules file generated by
+ * docuscope-rules.
+ *
+ * @param path: dictionary directory, .zip, .tar.gz, or .tgz archive, or rules file.
+ * @param policy: shortRules policy used when building rules for a directory.
+ */
+func Open(path string, policy rules.Policy) (*Tagger, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	if info.IsDir() || dictionary.IsArchive(path) {
+		d, err := dictionary.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		return FromDictionary(d, policy)
+	}
+
+	dict, err := rules.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(dict), nil
+}
+
+/**
+ * Returns a Tagger for the rules built from a loaded dictionary.
+ *
+ * @param policy: shortRules policy for single word patterns used by
+ *   multiple LATs.
+ */
+func FromDictionary(d *dictionary.Dictionary, policy rules.Policy) (*Tagger, error) {
+	dict, err := rules.Build(d, policy)
+	if err != nil {
+		return nil, err
+	}
+	return New(dict), nil
+}
+
+/**
+ * Reads the text of a file, or standard input if the file is "-".
+ */
+func ReadText(file string) (string, error) {
+	var b []byte
+	var err error
+	if file == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(filepath.Clean(file))
+	}
+	if err != nil {
+		return "", &dicterr.ReadError{Path: file, Err: err}
+	}
+	return string(b), nil
+}
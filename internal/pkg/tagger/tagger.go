@@ -0,0 +1,203 @@
+/*
+Package tagger is a reference implementation of DocuScope LAT tagging using
+the rules generated by docuscope-rules.
+
+Text is split into tokens and, from left to right, the longest pattern that
+matches starting at each token is tagged with its LAT.  A pattern element
+matches a token if it is the token itself or a !CLASS that the words map
+lists for the token.  Two word and longer patterns are looked up in the
+rules by their initial bigram and single word patterns in the shortRules.
+When patterns of the same length match, the one with fewer !CLASS elements
+is preferred, then the LAT id that sorts first.
+*/
+package tagger
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/rules"
+)
+
+var tokenRe = regexp.MustCompile(`[\w'-]+|[!"#$%&'()*+,-./:;<=>?@[\]^_\` + "`" + `{|}~]`)
+
+// Token is a word or punctuation mark in the text.
+type Token struct {
+	Word   string // lower case form used for matching
+	Text   string // as it appears in the text
+	Offset int    // byte offset of Text in the text
+}
+
+/**
+ * Splits text into words and punctuation marks.  Unlike the dictionary
+ * patterns, where ! and ? may be part of a word for the !CLASS markers,
+ * every ! and ? in the text is a token of its own, so a pattern such as
+ * "really?" never matches while "really ?" does.
+ */
+func Tokenize(text string) []Token {
+	indexes := tokenRe.FindAllStringIndex(text, -1)
+	tokens := make([]Token, len(indexes))
+	for i, ix := range indexes {
+		tokens[i] = Token{
+			Word:   strings.ToLower(text[ix[0]:ix[1]]),
+			Text:   text[ix[0]:ix[1]],
+			Offset: ix[0],
+		}
+	}
+	return tokens
+}
+
+// Span is a sequence of tokens, [Start, End), tagged with a LAT.
+type Span struct {
+	Start   int      `json:"start"`
+	End     int      `json:"end"`
+	Lat     string   `json:"lat"`
+	Pattern []string `json:"pattern"`
+}
+
+// Tagger tags tokens using the rules from a DocuScopeDictionary.
+type Tagger struct {
+	dict *rules.DocuScopeDictionary
+}
+
+func New(dict *rules.DocuScopeDictionary) *Tagger {
+	if dict.ShortRules == nil {
+		dict.ShortRules = rules.NewShortRules(rules.LastWins)
+	}
+	return &Tagger{dict}
+}
+
+/**
+ * Reads the JSON output of docuscope-rules and returns a Tagger for it.
+ */
+func Load(r io.Reader) (*Tagger, error) {
+	var dict rules.DocuScopeDictionary
+	if err := json.NewDecoder(r).Decode(&dict); err != nil {
+		return nil, err
+	}
+	return New(&dict), nil
+}
+
+/**
+ * Returns the word followed by the classes it belongs to.
+ */
+func (t *Tagger) expand(word string) []string {
+	if wds, ok := t.dict.Words[word]; ok {
+		return wds
+	}
+	return []string{word}
+}
+
+func (t *Tagger) matches(element string, token Token) bool {
+	for _, w := range t.expand(token.Word) {
+		if w == element {
+			return true
+		}
+	}
+	return false
+}
+
+func classCount(pattern []string) int {
+	count := 0
+	for _, w := range pattern {
+		if len(w) > 1 && strings.HasPrefix(w, "!") {
+			count++
+		}
+	}
+	return count
+}
+
+/**
+ * Reports if candidate is a better match than the current best.
+ */
+func better(candidate Span, best *Span) bool {
+	switch {
+	case best == nil:
+		return true
+	case candidate.End != best.End:
+		return candidate.End > best.End
+	}
+	cc, bc := classCount(candidate.Pattern), classCount(best.Pattern)
+	if cc != bc {
+		return cc < bc
+	}
+	return candidate.Lat < best.Lat
+}
+
+/**
+ * Finds the best pattern that matches starting at tokens[start].
+ */
+func (t *Tagger) match(tokens []Token, start int) *Span {
+	var best *Span
+	if start+1 < len(tokens) {
+		for _, first := range t.expand(tokens[start].Word) {
+			seconds, ok := t.dict.Rules[first]
+			if !ok {
+				continue
+			}
+			for _, second := range t.expand(tokens[start+1].Word) {
+				for lat, rests := range seconds[second] {
+				next:
+					for _, rest := range rests {
+						end := start + 2 + len(rest)
+						if end > len(tokens) {
+							continue
+						}
+						for k, element := range rest {
+							if !t.matches(element, tokens[start+2+k]) {
+								continue next
+							}
+						}
+						pattern := append([]string{first, second}, rest...)
+						if candidate := (Span{start, end, lat, pattern}); better(candidate, best) {
+							best = &candidate
+						}
+					}
+				}
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	for _, word := range t.expand(tokens[start].Word) {
+		lats := t.dict.ShortRules.Get(word)
+		if len(lats) > 0 {
+			candidate := Span{start, start + 1, lats[0], []string{word}}
+			if better(candidate, best) {
+				best = &candidate
+			}
+		}
+	}
+	return best
+}
+
+/**
+ * Tags the tokens returning the LAT spans in order.
+ * Tokens that do not match any pattern are not in any span.
+ */
+func (t *Tagger) Tag(tokens []Token) []Span {
+	var spans []Span
+	for i := 0; i < len(tokens); {
+		if span := t.match(tokens, i); span != nil {
+			spans = append(spans, *span)
+			i = span.End
+		} else {
+			i++
+		}
+	}
+	return spans
+}
+
+/**
+ * Counts the number of spans tagged with each LAT.
+ */
+func Count(spans []Span) map[string]int {
+	counts := make(map[string]int)
+	for _, span := range spans {
+		counts[span.Lat]++
+	}
+	return counts
+}
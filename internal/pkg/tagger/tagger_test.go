@@ -0,0 +1,61 @@
+package tagger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const rulesJSON = `{
+  "rules": {
+    "i": {
+      "think": {"Confidence": [[], ["that"]]},
+      "!BELIEVE": {"Hedge": [["!GREET"]]}
+    },
+    "!GREET": {"there": {"Greeting": [[]]}}
+  },
+  "shortRules": {"wow": "Surprise", "!GREET": "Greeting"},
+  "words": {
+    "hello": ["hello", "!GREET"],
+    "hi": ["hi", "!GREET"],
+    "suppose": ["suppose", "!BELIEVE"]
+  }
+}`
+
+func TestTag(t *testing.T) {
+	tagger, err := Load(strings.NewReader(rulesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := "Wow, I think that I suppose hi there. Hello!"
+	expected := []Span{
+		{0, 1, "Surprise", []string{"wow"}},
+		{2, 5, "Confidence", []string{"i", "think", "that"}},
+		{5, 8, "Hedge", []string{"i", "!BELIEVE", "!GREET"}},
+		{10, 11, "Greeting", []string{"!GREET"}},
+	}
+	actual := tagger.Tag(Tokenize(test))
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q to be tagged as %v but instead got %v!", test, expected, actual)
+	}
+}
+
+func TestTagAttachedPunctuation(t *testing.T) {
+	tagger, err := Load(strings.NewReader(`{
+  "rules": {"really": {"?": {"Doubt": [[]]}}},
+  "shortRules": {"really?": "Doubt", "wow!": "Surprise"},
+  "words": {}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := "Really? Wow!"
+	// The ? and ! are tokens of their own, so only the two word pattern matches.
+	expected := []Span{{0, 2, "Doubt", []string{"really", "?"}}}
+	actual := tagger.Tag(Tokenize(test))
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %q to be tagged as %v but instead got %v!", test, expected, actual)
+	}
+}
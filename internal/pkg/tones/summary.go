@@ -0,0 +1,98 @@
+package tones
+
+import (
+	"sort"
+)
+
+/*
+Summary is the LAT hit counts for a text rolled up to the dimensions and
+clusters of the tones.  PerThousand counts are normalized by the number of
+tokens in the text.  LAT hits that are not assigned to any dimension are
+listed in Unassigned.
+*/
+type Summary struct {
+	Tokens     int              `json:"tokens"`
+	Count      int              `json:"count"`
+	Clusters   []ClusterSummary `json:"clusters"`
+	Unassigned map[string]int   `json:"unassigned,omitempty"`
+}
+
+type ClusterSummary struct {
+	Name        string             `json:"name"`
+	Count       int                `json:"count"`
+	PerThousand float64            `json:"per1000"`
+	Dimensions  []DimensionSummary `json:"dimensions"`
+}
+
+type DimensionSummary struct {
+	Name        string         `json:"name"`
+	Count       int            `json:"count"`
+	PerThousand float64        `json:"per1000"`
+	Lats        map[string]int `json:"lats"`
+}
+
+func perThousand(count int, tokens int) float64 {
+	if tokens == 0 {
+		return 0
+	}
+	return float64(count) * 1000 / float64(tokens)
+}
+
+/**
+ * Aggregates LAT hit counts into dimension and cluster totals.
+ * Clusters and dimensions are sorted by name and include those without hits.
+ * A LAT assigned to more than one dimension is counted in each of them.
+ *
+ * @param t: the tones hierarchy.
+ * @param latCounts: the number of hits for each LAT.
+ * @param tokens: the number of tokens in the text.
+ */
+func Summarize(t Tones, latCounts map[string]int, tokens int) Summary {
+	summary := Summary{Tokens: tokens, Clusters: []ClusterSummary{}}
+	assigned := make(map[string]bool)
+	for _, count := range latCounts {
+		summary.Count += count
+	}
+	for _, cluster := range sortedKeys(t) {
+		cs := ClusterSummary{Name: cluster, Dimensions: []DimensionSummary{}}
+		dimensions := t[cluster]
+		dimensionNames := make([]string, 0, len(dimensions))
+		for dimension := range dimensions {
+			dimensionNames = append(dimensionNames, dimension)
+		}
+		sort.Strings(dimensionNames)
+		for _, dimension := range dimensionNames {
+			ds := DimensionSummary{Name: dimension, Lats: make(map[string]int)}
+			for _, lat := range dimensions[dimension] {
+				assigned[lat] = true
+				if count := latCounts[lat]; count > 0 {
+					ds.Lats[lat] = count
+					ds.Count += count
+				}
+			}
+			ds.PerThousand = perThousand(ds.Count, tokens)
+			cs.Count += ds.Count
+			cs.Dimensions = append(cs.Dimensions, ds)
+		}
+		cs.PerThousand = perThousand(cs.Count, tokens)
+		summary.Clusters = append(summary.Clusters, cs)
+	}
+	for lat, count := range latCounts {
+		if !assigned[lat] && count > 0 {
+			if summary.Unassigned == nil {
+				summary.Unassigned = make(map[string]int)
+			}
+			summary.Unassigned[lat] = count
+		}
+	}
+	return summary
+}
+
+func sortedKeys(t Tones) []string {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
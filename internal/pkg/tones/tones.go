@@ -0,0 +1,225 @@
+package tones
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+/*
+Tones is the cluster to dimension to LAT ids hierarchy defined in a
+DocuScope dictionary _tones.txt file.
+*/
+type Tones map[string]map[string][]string
+
+/**
+ * Add lats to the given cluster and dimension.
+ */
+func Add(m Tones, cluster string, dimension string, lats []string) {
+	mm, ok := m[cluster]
+	if !ok {
+		mm = make(map[string][]string)
+		m[cluster] = mm
+	}
+	// pushnew lats onto existing (no duplicates).
+	// This handles the problem where a given tone is repeated.
+	// This should probably be broader to check for no lat duplicates
+	// as that will cause errors in docuscope-tag as it will complain
+	// that indicies should be unique.
+	for _, lat := range lats {
+		to_add := true
+		for _, ele := range mm[dimension] {
+			if ele == lat {
+				to_add = false
+				break
+			}
+		}
+		if to_add {
+			mm[dimension] = append(mm[dimension], lat)
+		}
+	}
+}
+
+// Dimension is a DIMENSION declaration in a _tones.txt file.
+type Dimension struct {
+	Cluster string
+	Name    string
+	Line    int
+}
+
+// Entry is a LAT listed under a dimension in a _tones.txt file.
+type Entry struct {
+	Cluster   string
+	Dimension string
+	Kind      string // LAT, LAT*, or CLASS
+	Lat       string
+	Line      int
+}
+
+// File is the parsed content of a _tones.txt file in file order.
+type File struct {
+	Path       string
+	Dimensions []Dimension
+	Entries    []Entry
+}
+
+/**
+ * Parses the contents of a _tones.txt file.
+ *
+ * @param r: reader for the _tones.txt content.
+ * @param path: name of the content used when reporting errors.
+ * @return a *dicterr.LineTooLongError or *dicterr.ReadError if the content
+ *   can not be read.
+ */
+func Parse(r io.Reader, path string) (*File, error) {
+	var cluster string
+	var dimension string
+	f := &File{Path: path}
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.Fields(scanner.Text())
+		if len(line) > 1 {
+			switch line[0] {
+			case "CLUSTER:":
+				cluster = line[1]
+			case "DIMENSION:":
+				dimension = line[1]
+				f.Dimensions = append(f.Dimensions, Dimension{cluster, dimension, lineNumber})
+			case "LAT:", "LAT*:", "CLASS:":
+				kind := strings.TrimSuffix(line[0], ":")
+				for _, lat := range line[1:] {
+					f.Entries = append(f.Entries, Entry{cluster, dimension, kind, lat, lineNumber})
+				}
+			default:
+				//noop
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, dicterr.Scan(path, lineNumber+1, err)
+	}
+	return f, nil
+}
+
+/**
+ * Parses the _tones.txt file at the given path.
+ */
+func ParseFile(path string) (*File, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, &dicterr.ReadError{Path: path, Err: err}
+	}
+	defer file.Close()
+	return Parse(file, path)
+}
+
+/**
+ * Returns the cluster to dimension to LAT ids hierarchy of the file.
+ */
+func (f *File) Tones() Tones {
+	tones := make(Tones)
+	for _, e := range f.Entries {
+		//add to tones
+		Add(tones, e.Cluster, e.Dimension, []string{e.Lat})
+	}
+	return tones
+}
+
+/*
+ExtendedTones is the cluster to dimension to listings hierarchy which keeps
+the kind of each listing.
+*/
+type ExtendedTones map[string]map[string][]Listing
+
+// Listing is a LAT id and the kind of line it was listed on.
+type Listing struct {
+	Lat  string `json:"lat"`
+	Kind string `json:"kind"` // LAT, LAT*, or CLASS
+}
+
+/**
+ * Returns the cluster to dimension to listings hierarchy of the file.
+ * As with Tones, repeated listings of the same kind are only included once.
+ */
+func (f *File) ExtendedTones() ExtendedTones {
+	tones := make(ExtendedTones)
+	for _, e := range f.Entries {
+		mm, ok := tones[e.Cluster]
+		if !ok {
+			mm = make(map[string][]Listing)
+			tones[e.Cluster] = mm
+		}
+		listing := Listing{e.Lat, e.Kind}
+		toAdd := true
+		for _, ele := range mm[e.Dimension] {
+			if ele == listing {
+				toAdd = false
+				break
+			}
+		}
+		if toAdd {
+			mm[e.Dimension] = append(mm[e.Dimension], listing)
+		}
+	}
+	return tones
+}
+
+/**
+ * Reads the contents of a _tones.txt file.
+ *
+ * @param r: reader for the _tones.txt content.
+ * @param path: name of the content used when reporting errors.
+ * @return errors as Parse.
+ */
+func Read(r io.Reader, path string) (Tones, error) {
+	f, err := Parse(r, path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Tones(), nil
+}
+
+/**
+ * Reads the _tones.txt file at the given path.
+ */
+func ReadFile(path string) (Tones, error) {
+	f, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Tones(), nil
+}
+
+/**
+ * Sorts the LAT ids of each dimension.
+ */
+func (t Tones) Sort() {
+	for _, dimensions := range t {
+		for _, lats := range dimensions {
+			sort.Strings(lats)
+		}
+	}
+}
+
+/**
+ * Sorts the listings of each dimension by LAT id and then kind.
+ */
+func (t ExtendedTones) Sort() {
+	for _, dimensions := range t {
+		for _, listings := range dimensions {
+			sort.Slice(listings, func(i, j int) bool {
+				if listings[i].Lat != listings[j].Lat {
+					return listings[i].Lat < listings[j].Lat
+				}
+				return listings[i].Kind < listings[j].Kind
+			})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package tones
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tonesTxt = `CLUSTER: Reasoning
+DIMENSION: Because
+LAT: Cause Effect
+LAT*: Cause
+
+CLUSTER: Narrative
+DIMENSION: Because
+CLASS: Effect Typo
+`
+
+func TestValidate(t *testing.T) {
+	f, err := Parse(strings.NewReader(tonesTxt), "_tones.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"_tones.txt:8: error: CLASS Typo in Narrative/Because is not a LAT in the dictionary [missing-lat]",
+		"_tones.txt:3: error: Effect is in Reasoning/Because and also Narrative/Because (line 8) [multiple-dimensions]",
+		"_tones.txt:7: error: dimension Because in cluster Narrative was already declared in cluster Reasoning at line 2 [duplicate-dimension]",
+		"_tones.txt: warning: LAT Unused is not assigned to any dimension [unassigned-lat]",
+	}
+	var actual []string
+	for _, p := range f.Validate([]string{"Cause", "Effect", "Unused"}) {
+		actual = append(actual, p.String())
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected problems\n%s\nbut instead got\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestAdd(t *testing.T) {
+	m := make(Tones)
+	Add(m, "Confidence", "Certain", []string{"Confidence", "Sure"})
+	Add(m, "Confidence", "Certain", []string{"Sure", "Definite"})
+	Add(m, "Other", "Excited", []string{"Exclamation"})
+	expected := Tones{
+		"Confidence": {"Certain": {"Confidence", "Sure", "Definite"}},
+		"Other":      {"Excited": {"Exclamation"}},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Expected tones %v but instead got %v!", expected, m)
+	}
+}
@@ -0,0 +1,94 @@
+package tones
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem is an inconsistency between a _tones.txt file and the LATs.
+type Problem struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"` // error or warning
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	location := p.Path
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", p.Path, p.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", location, p.Severity, p.Message, p.Check)
+}
+
+/**
+ * Checks the tones against the LATs of the dictionary.
+ * Reports, in this order:
+ *   missing-lat (error): entries that are not a LAT in the dictionary.
+ *   multiple-dimensions (error): LATs assigned to more than one dimension.
+ *   duplicate-dimension (error): dimension names declared more than once.
+ *   unassigned-lat (warning): dictionary LATs not assigned to a dimension.
+ *
+ * @param lats: the ids of the LATs in the dictionary.
+ */
+func (f *File) Validate(lats []string) []Problem {
+	var problems []Problem
+	known := make(map[string]bool, len(lats))
+	for _, lat := range lats {
+		known[lat] = true
+	}
+
+	assigned := make(map[string][]Entry)
+	for _, e := range f.Entries {
+		if !known[e.Lat] {
+			problems = append(problems, Problem{"missing-lat", "error", f.Path, e.Line,
+				fmt.Sprintf("%s %s in %s/%s is not a LAT in the dictionary", e.Kind, e.Lat, e.Cluster, e.Dimension)})
+		}
+		assigned[e.Lat] = append(assigned[e.Lat], e)
+	}
+
+	reported := make(map[string]bool)
+	for _, e := range f.Entries {
+		if reported[e.Lat] {
+			continue // report each LAT once, at its first entry
+		}
+		reported[e.Lat] = true
+		entries := assigned[e.Lat]
+		var others []string
+		for _, other := range entries[1:] {
+			if other.Cluster != e.Cluster || other.Dimension != e.Dimension {
+				others = append(others, fmt.Sprintf("%s/%s (line %d)", other.Cluster, other.Dimension, other.Line))
+			}
+		}
+		if len(others) > 0 {
+			problems = append(problems, Problem{"multiple-dimensions", "error", f.Path, e.Line,
+				fmt.Sprintf("%s is in %s/%s and also %s", e.Lat, e.Cluster, e.Dimension, strings.Join(others, ", "))})
+		}
+	}
+
+	declared := make(map[string]Dimension)
+	for _, d := range f.Dimensions {
+		if first, ok := declared[d.Name]; ok {
+			problems = append(problems, Problem{"duplicate-dimension", "error", f.Path, d.Line,
+				fmt.Sprintf("dimension %s in cluster %s was already declared in cluster %s at line %d",
+					d.Name, d.Cluster, first.Cluster, first.Line)})
+		} else {
+			declared[d.Name] = d
+		}
+	}
+
+	unassigned := make([]string, 0)
+	for lat := range known {
+		if _, ok := assigned[lat]; !ok {
+			unassigned = append(unassigned, lat)
+		}
+	}
+	sort.Strings(unassigned)
+	for _, lat := range unassigned {
+		problems = append(problems, Problem{"unassigned-lat", "warning", f.Path, 0,
+			fmt.Sprintf("LAT %s is not assigned to any dimension", lat)})
+	}
+	return problems
+}
@@ -2,47 +2,121 @@ package wordclasses
 
 import (
 	"bufio"
-	"log"
-	"os"
-	"path/filepath"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
 	"strings"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
 )
 
+// Class is a word class as defined in a _wordclasses.txt file.
+type Class struct {
+	Name    string // !CLASS name
+	Members []string
+	Line    int // line of the class declaration
+}
+
 /**
  * Reads _wordclasses.txt file associated with a DocuScope dictionary.
  *
  * @param words: the map of word class to array of members.
- * @param wordclassesPath: location of the _wordclasses.txt file.
+ * @param fsys: the file system containing the dictionary, eg. os.DirFS(dir).
+ * @param wordclassesPath: name of the _wordclasses.txt file in fsys.
+ * @return a *dicterr.MissingWordClassesError if the file does not exist,
+ *   a *dicterr.LineTooLongError or *dicterr.ReadError if it can not be read.
+ */
+func ReadWords(words map[string][]string, fsys fs.FS, wordclassesPath string) error {
+	classes, err := ReadClasses(fsys, wordclassesPath)
+	if err != nil {
+		return err
+	}
+	AddWords(words, classes)
+	return nil
+}
+
+/**
+ * Adds the members of the classes to the map of words to the word and
+ * its classes.
+ *
+ * @param words: the map of word class to array of members.
+ * @param classes: the word classes in file order.
+ */
+func AddWords(words map[string][]string, classes []Class) {
+	for _, class := range classes {
+		for _, word := range class.Members {
+			_, ok := words[word]
+			if !ok {
+				words[word] = append(words[word], word)
+			}
+			words[word] = pushnew(words[word], class.Name)
+		}
+	}
+}
+
+/**
+ * Reads the word classes, in file order, from the _wordclasses.txt file
+ * associated with a DocuScope dictionary.
+ *
+ * @param fsys: the file system containing the dictionary.
+ * @param wordclassesPath: name of the _wordclasses.txt file in fsys.
+ * @return errors as ReadWords.
  */
-func ReadWords(words map[string][]string, wordclassesPath string) {
-	curClass := "NONE"
-	wordclasses, err := os.Open(filepath.Clean(wordclassesPath))
+func ReadClasses(fsys fs.FS, wordclassesPath string) ([]Class, error) {
+	wordclasses, err := fsys.Open(wordclassesPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &dicterr.MissingWordClassesError{Path: wordclassesPath, Err: err}
+	} else if err != nil {
+		return nil, &dicterr.ReadError{Path: wordclassesPath, Err: err}
+	}
+	defer wordclasses.Close()
+
+	classes, err := Parse(wordclasses, wordclassesPath)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	scanner := bufio.NewScanner(wordclasses)
+	if err := wordclasses.Close(); err != nil {
+		return nil, &dicterr.ReadError{Path: wordclassesPath, Err: err}
+	}
+	return classes, nil
+}
+
+/**
+ * Parses the word classes, in file order, from the contents of a
+ * _wordclasses.txt file.
+ * Words listed before the first class declaration are members of the
+ * class NONE.
+ *
+ * @param path: used for error reporting.
+ */
+func Parse(r io.Reader, path string) ([]Class, error) {
+	var classes []Class
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
+		lineNumber++
 		line := strings.Fields(scanner.Text())
 		switch len(line) {
 		case 1:
-			word := strings.ToLower(line[0])
-			_, ok := words[word]
-			if !ok {
-				words[word] = append(words[word], word)
+			if len(classes) == 0 {
+				classes = append(classes, Class{Name: "NONE"})
 			}
-			words[word] = pushnew(words[word], curClass)
+			cur := &classes[len(classes)-1]
+			cur.Members = append(cur.Members, strings.ToLower(line[0]))
 		case 2:
-			curClass = "!" + strings.ToUpper(line[1])
+			classes = append(classes, Class{
+				Name: "!" + strings.ToUpper(line[1]),
+				Line: lineNumber,
+			})
 		default:
 			// noop
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		panic(err)
-	}
-	if err := wordclasses.Close(); err != nil {
-		log.Fatal("Could not close word classes file: ", err)
+		return nil, dicterr.Scan(path, lineNumber+1, err)
 	}
+	return classes, nil
 }
 
 // append only if not already an element of the slice.
@@ -54,3 +128,15 @@ func pushnew(slice []string, val string) []string {
 	}
 	return append(slice, val)
 }
+
+/**
+ * Sorts the classes of each word in the map of words to the word and its
+ * classes, leaving the word itself first.
+ */
+func Sort(words map[string][]string) {
+	for _, classes := range words {
+		if len(classes) > 1 {
+			sort.Strings(classes[1:])
+		}
+	}
+}
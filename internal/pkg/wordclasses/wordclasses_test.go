@@ -0,0 +1,34 @@
+package wordclasses
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"gitlab.com/CMU_Sidecar/docuscope-dictionary-tools/docuscope-rules/internal/pkg/dicterr"
+)
+
+func TestReadWords(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_wordclasses.txt": {Data: []byte("stray\nCLASS: greet\nHello\nhi\n\nCLASS: BANG\nhi\n\n")},
+	}
+	words := make(map[string][]string)
+	if err := ReadWords(words, fsys, "_wordclasses.txt"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"stray": {"stray", "NONE"},
+		"hello": {"hello", "!GREET"},
+		"hi":    {"hi", "!GREET", "!BANG"},
+	}
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("Expected words %v but instead got %v!", expected, words)
+	}
+
+	err := ReadWords(words, fsys, "missing/_wordclasses.txt")
+	var missing *dicterr.MissingWordClassesError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingWordClassesError but instead got %v!", err)
+	}
+}